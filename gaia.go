@@ -1,6 +1,7 @@
 package gaia
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -28,6 +29,18 @@ type Mode string
 // WorkerStatus represents the different status a worker can have
 type WorkerStatus string
 
+// CleanupPolicy represents the different policies for removing
+// the temporary build folder after a build.
+type CleanupPolicy string
+
+// NotificationPolicy represents when build notifications of a
+// pipeline are sent.
+type NotificationPolicy string
+
+// VCSType represents the different version control systems
+// the sources of a pipeline can be fetched from.
+type VCSType string
+
 const (
 	// PTypeUnknown unknown plugin type
 	PTypeUnknown PipelineType = "unknown"
@@ -50,6 +63,15 @@ const (
 	// PTypeNodeJS NodeJS plugin type
 	PTypeNodeJS PipelineType = "nodejs"
 
+	// PTypePerl perl plugin type
+	PTypePerl PipelineType = "perl"
+
+	// PTypeR R plugin type
+	PTypeR PipelineType = "r"
+
+	// PTypeHaskell haskell plugin type
+	PTypeHaskell PipelineType = "haskell"
+
 	// CreatePipelineFailed status
 	CreatePipelineFailed CreatePipelineType = "failed"
 
@@ -59,6 +81,10 @@ const (
 	// CreatePipelineSuccess status
 	CreatePipelineSuccess CreatePipelineType = "success"
 
+	// CreatePipelineUpToDate status. The build has been skipped because
+	// nothing changed and the existing binary has been reused.
+	CreatePipelineUpToDate CreatePipelineType = "uptodate"
+
 	// RunNotScheduled status
 	RunNotScheduled PipelineRunStatus = "not scheduled"
 
@@ -107,6 +133,33 @@ const (
 	// WorkerSuspended status
 	WorkerSuspended WorkerStatus = "suspended"
 
+	// CleanupAlways removes the build folder after every build
+	CleanupAlways CleanupPolicy = "always"
+
+	// CleanupOnSuccess removes the build folder only after a successful build
+	CleanupOnSuccess CleanupPolicy = "onsuccess"
+
+	// CleanupNever keeps the build folder after every build
+	CleanupNever CleanupPolicy = "never"
+
+	// NotifyAlways sends a notification after every build
+	NotifyAlways NotificationPolicy = "always"
+
+	// NotifyOnFailure sends a notification only after a failed build
+	NotifyOnFailure NotificationPolicy = "onfailure"
+
+	// NotifyMuted never sends a notification
+	NotifyMuted NotificationPolicy = "muted"
+
+	// VCSGit git version control system
+	VCSGit VCSType = "git"
+
+	// VCSMercurial mercurial version control system
+	VCSMercurial VCSType = "hg"
+
+	// VCSSubversion subversion version control system
+	VCSSubversion VCSType = "svn"
+
 	// LogsFolderName represents the Name of the logs folder in pipeline run folder
 	LogsFolderName = "logs"
 
@@ -134,6 +187,15 @@ const (
 	// TmpNodeJSFolder is the name of the nodejs temporary folder
 	TmpNodeJSFolder = "nodejs"
 
+	// TmpPerlFolder is the name of the perl temporary folder
+	TmpPerlFolder = "perl"
+
+	// TmpRFolder is the name of the R temporary folder
+	TmpRFolder = "r"
+
+	// TmpHaskellFolder is the name of the haskell temporary folder
+	TmpHaskellFolder = "haskell"
+
 	// WorkerRegisterKey is the used key for worker registration secret
 	WorkerRegisterKey = "WORKER_REGISTER_KEY"
 
@@ -195,7 +257,69 @@ type Pipeline struct {
 	PeriodicSchedules []string     `json:"periodicschedules,omitempty"`
 	TriggerToken      string       `json:"trigger_token,omitempty"`
 	Tags              []string     `json:"tags,omitempty"`
+	ImageRef          string       `json:"imageref,omitempty"`
+	DefaultArgs       []*Argument  `json:"defaultargs,omitempty"`
 	CronInst          *cron.Cron   `json:"-"`
+
+	// Fingerprint records the inputs and the output of the last build.
+	Fingerprint *BuildFingerprint `json:"fingerprint,omitempty"`
+
+	// NotificationPolicy defines when build notifications are sent.
+	// Defaults to notifications on failed builds only.
+	NotificationPolicy NotificationPolicy `json:"notificationpolicy,omitempty"`
+
+	// PluginModule is true if the pipeline has been built as go plugin
+	// module which is loaded in-process instead of being executed.
+	PluginModule bool `json:"pluginmodule,omitempty"`
+
+	// Metadata is read from the manifest in the source root of the pipeline.
+	Metadata *PipelineMetadata `json:"metadata,omitempty"`
+
+	// Entrypoint is the script which is started for interpreted pipelines,
+	// relative to the source root. The default of the type is used if empty.
+	Entrypoint string `json:"entrypoint,omitempty"`
+
+	// PathFilters are the path filters of the pipeline. Updates of the
+	// repository which do not touch a matching file are not built.
+	PathFilters []string `json:"pathfilters,omitempty"`
+
+	// BuildOptions are the options of the build request the pipeline has
+	// been built with. Updates of the repository are built with them.
+	BuildOptions *BuildOptions `json:"buildoptions,omitempty"`
+}
+
+// PipelineMetadata describes a pipeline. The tags are informational
+// only, unlike the pipeline tags which select the workers.
+type PipelineMetadata struct {
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+}
+
+// BuildFingerprint identifies all inputs of a pipeline build and the
+// resulting artifact. Two builds with equal inputs must produce equal
+// output hashes to be reproducible.
+type BuildFingerprint struct {
+	// SourceHash is the hash of the source tree without the git folder.
+	SourceHash string `json:"sourcehash,omitempty"`
+
+	// Toolchain identifies the toolchain binaries used for the build.
+	Toolchain string `json:"toolchain,omitempty"`
+
+	// EnvHash is the hash of the build environment. Variables which
+	// look like secrets are not included.
+	EnvHash string `json:"envhash,omitempty"`
+
+	// Flags are the build options of the pipeline.
+	Flags map[string]string `json:"flags,omitempty"`
+
+	// SpecHash is the hash of all options of the build request which
+	// affect the build result or the stored pipeline. It is not part of
+	// the reproducibility check because a rebuild only restores the flags.
+	SpecHash string `json:"spechash,omitempty"`
+
+	// OutputHash is the hash of the build result.
+	OutputHash string `json:"outputhash,omitempty"`
 }
 
 // GitRepo represents a single git repository
@@ -206,7 +330,14 @@ type GitRepo struct {
 	PrivateKey     PrivateKey `json:"privatekey,omitempty"`
 	SelectedBranch string     `json:"selectedbranch,omitempty"`
 	Branches       []string   `json:"branches,omitempty"`
+	Revision       string     `json:"revision,omitempty"`
 	LocalDest      string     `json:"-"`
+
+	// SkipSubmodules disables the initialization of git submodules.
+	SkipSubmodules bool `json:"skipsubmodules,omitempty"`
+
+	// PinnedRevision is checked out instead of the head of the selected branch.
+	PinnedRevision string `json:"pinnedrevision,omitempty"`
 }
 
 // Job represents a single job of a pipeline
@@ -238,6 +369,138 @@ type CreatePipeline struct {
 	Output      string             `json:"output,omitempty"`
 	Created     time.Time          `json:"created,omitempty"`
 	GitHubToken string             `json:"githubtoken,omitempty"`
+
+	// Target defines the platform the pipeline is compiled for.
+	// If not set, the pipeline is compiled for the host platform.
+	Target *BuildTarget `json:"target,omitempty"`
+
+	// Matrix defines additional platforms the pipeline is compiled for.
+	Matrix        []BuildTarget       `json:"matrix,omitempty"`
+	MatrixResults []BuildTargetResult `json:"matrixresults,omitempty"`
+
+	// MatrixEntry is set for the builds of the entries of a build matrix.
+	// Their results are never loaded as pipelines.
+	MatrixEntry bool `json:"-"`
+
+	// EnvFile is the path to an env file relative to the repository root.
+	// The variables are added to the build environment.
+	EnvFile string `json:"envfile,omitempty"`
+
+	// VCS is the version control system the sources are fetched from.
+	// Defaults to git.
+	VCS VCSType `json:"vcs,omitempty"`
+
+	// SourcePath is an optional local source directory relative to the
+	// configured source root of the server. If set, the sources are copied
+	// from there instead of cloning the repository.
+	SourcePath string `json:"sourcepath,omitempty"`
+
+	// Image builds a container image instead of a binary if set.
+	Image *ImageOptions `json:"image,omitempty"`
+
+	// RequireCleanTree fails the build if the cloned git working tree
+	// contains uncommitted changes.
+	RequireCleanTree bool `json:"requirecleantree,omitempty"`
+
+	// Replace maps go module paths to local directories relative to the
+	// configured source root of the server. The directories are copied
+	// into the build context and replace the modules in the go.mod file
+	// of go pipelines.
+	Replace map[string]string `json:"replace,omitempty"`
+
+	// Lint runs the static analysis after the compile step of pipelines
+	// which support it. Findings fail the build.
+	Lint bool `json:"lint,omitempty"`
+
+	// GoPlugin builds go pipelines as go plugin module (.so) which is
+	// loaded in-process. The host must support the go plugin mode.
+	GoPlugin bool `json:"goplugin,omitempty"`
+
+	// TinyGo compiles go pipelines with TinyGo instead of the go compiler.
+	// TinyGoTarget is the TinyGo target, e.g. "wasi" or "arduino".
+	// The binary is built for the host if no target has been defined.
+	TinyGo       bool   `json:"tinygo,omitempty"`
+	TinyGoTarget string `json:"tinygotarget,omitempty"`
+
+	// IdempotencyKey deduplicates build requests. A build submitted with
+	// the key of a build which is in flight or has been finished recently
+	// is not started again.
+	IdempotencyKey string `json:"idempotencykey,omitempty"`
+
+	// PathFilters are glob patterns of the files the pipeline is built
+	// from. The build is skipped if no file which matches one of the
+	// patterns has changed since the revision of the last build.
+	PathFilters []string `json:"pathfilters,omitempty"`
+
+	// Artifacts are glob patterns of additional files in the source tree,
+	// e.g. templates or configuration files, which are copied next to the
+	// binary into the plugins folder. Every pattern must match a file.
+	Artifacts []string `json:"artifacts,omitempty"`
+
+	// PreserveSource archives the source tree next to the binary in the
+	// plugins folder for debugging.
+	PreserveSource bool `json:"preservesource,omitempty"`
+
+	// Entrypoint is the script which starts the pipeline, relative to the
+	// source root. Only supported for NodeJS, Perl and R pipelines.
+	Entrypoint string `json:"entrypoint,omitempty"`
+
+	// SmokeTestFlag is passed to the built binary of go, C++ and haskell
+	// pipelines, e.g. "--version", before it is copied to the plugins
+	// folder. The build fails if the binary does not exit zero within
+	// SmokeTestTimeout seconds. No smoke test is run if the flag is empty.
+	SmokeTestFlag    string `json:"smoketestflag,omitempty"`
+	SmokeTestTimeout int    `json:"smoketesttimeout,omitempty"`
+}
+
+// BuildOptions are the options of a build request which are stored on the
+// pipeline. See CreatePipeline for the meaning of the fields.
+type BuildOptions struct {
+	Target           *BuildTarget      `json:"target,omitempty"`
+	Matrix           []BuildTarget     `json:"matrix,omitempty"`
+	EnvFile          string            `json:"envfile,omitempty"`
+	VCS              VCSType           `json:"vcs,omitempty"`
+	SourcePath       string            `json:"sourcepath,omitempty"`
+	Image            *ImageOptions     `json:"image,omitempty"`
+	RequireCleanTree bool              `json:"requirecleantree,omitempty"`
+	Replace          map[string]string `json:"replace,omitempty"`
+	Lint             bool              `json:"lint,omitempty"`
+	GoPlugin         bool              `json:"goplugin,omitempty"`
+	TinyGo           bool              `json:"tinygo,omitempty"`
+	TinyGoTarget     string            `json:"tinygotarget,omitempty"`
+	Artifacts        []string          `json:"artifacts,omitempty"`
+	PreserveSource   bool              `json:"preservesource,omitempty"`
+	Entrypoint       string            `json:"entrypoint,omitempty"`
+	SmokeTestFlag    string            `json:"smoketestflag,omitempty"`
+	SmokeTestTimeout int               `json:"smoketesttimeout,omitempty"`
+}
+
+// ImageOptions defines how the container image of a pipeline is built.
+type ImageOptions struct {
+	// Registry is the registry the image is pushed to.
+	// If empty, the configured default registry is used.
+	Registry string `json:"registry,omitempty"`
+
+	// Dockerfile is the path to the Dockerfile relative to the repository root.
+	Dockerfile string `json:"dockerfile,omitempty"`
+
+	// Tag is the tag of the image. Defaults to "latest".
+	Tag string `json:"tag,omitempty"`
+}
+
+// BuildTarget represents a single operating system and architecture
+// combination a pipeline can be compiled for.
+type BuildTarget struct {
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+}
+
+// BuildTargetResult represents the result of a single build matrix entry.
+type BuildTargetResult struct {
+	Target     BuildTarget        `json:"target"`
+	Name       string             `json:"name,omitempty"`
+	StatusType CreatePipelineType `json:"statustype,omitempty"`
+	Output     string             `json:"output,omitempty"`
 }
 
 // PrivateKey represents a pem encoded private key
@@ -300,12 +563,28 @@ type Config struct {
 	PipelinePath       string
 	WorkspacePath      string
 	Worker             int
+	BuildWorker        int
+	BuildQueueSize     int
+	BuildHistorySize   int
 	JwtPrivateKeyPath  string
 	JWTKey             interface{}
 	Logger             hclog.Logger
 	CAPath             string
 	WorkerServerPort   string
 	PreventPrimaryWork bool
+	CleanupPolicy      CleanupPolicy
+	ImageRegistry      string
+	GoLinter           string
+	WasmRuntime        string
+	SourceRoot         string
+
+	// Build phase timeouts
+	BuildPrepareTimeout time.Duration
+	BuildCompileTimeout time.Duration
+
+	// BuildIdempotencyWindow is the time idempotency keys of build
+	// requests are remembered after the build has been finished
+	BuildIdempotencyWindow time.Duration
 
 	// Worker
 	WorkerName        string
@@ -317,6 +596,22 @@ type Config struct {
 	Bolt struct {
 		Mode os.FileMode
 	}
+
+	// Artifact defines mode, ownership and maximum size of the installed pipeline artifacts
+	Artifact struct {
+		ModeRaw string
+		Mode    os.FileMode
+		Group   string
+		MaxSize int64
+	}
+
+	// BuildProxy defines the proxy of the build commands. Empty values
+	// inherit the proxy of the server process.
+	BuildProxy struct {
+		HTTP    string
+		HTTPS   string
+		NoProxy string
+	}
 }
 
 // StoreConfig defines config settings to be stored in DB.
@@ -325,7 +620,50 @@ type StoreConfig struct {
 	Poll bool
 }
 
+// pipelineTypes contains all known pipeline types.
+var pipelineTypes = []PipelineType{
+	PTypeGolang,
+	PTypeJava,
+	PTypePython,
+	PTypeCpp,
+	PTypeRuby,
+	PTypeNodeJS,
+	PTypePerl,
+	PTypeR,
+	PTypeHaskell,
+}
+
 // String returns a pipeline type string back
 func (p PipelineType) String() string {
 	return string(p)
 }
+
+// pipelineTypeSource returns the pipeline types which can be built.
+// It is installed by the package which registers the build pipelines.
+var pipelineTypeSource func() []PipelineType
+
+// SetPipelineTypeSource installs the function which returns all pipeline
+// types which can be built. PipelineTypes and ParsePipelineType use it
+// instead of the built-in types so that types registered at runtime are known.
+func SetPipelineTypeSource(source func() []PipelineType) {
+	pipelineTypeSource = source
+}
+
+// PipelineTypes returns all known pipeline types.
+func PipelineTypes() []PipelineType {
+	if pipelineTypeSource != nil {
+		return pipelineTypeSource()
+	}
+	return append([]PipelineType{}, pipelineTypes...)
+}
+
+// ParsePipelineType returns the known pipeline type with the given
+// string representation.
+func ParsePipelineType(s string) (PipelineType, error) {
+	for _, t := range PipelineTypes() {
+		if t != PTypeUnknown && t.String() == s {
+			return t, nil
+		}
+	}
+	return PTypeUnknown, fmt.Errorf("unknown pipeline type %s", s)
+}
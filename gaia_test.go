@@ -0,0 +1,29 @@
+package gaia
+
+import "testing"
+
+func TestParsePipelineType(t *testing.T) {
+	for _, pType := range PipelineTypes() {
+		parsed, err := ParsePipelineType(pType.String())
+		if err != nil {
+			t.Fatalf("cannot parse pipeline type %s: %s", pType, err.Error())
+		}
+		if parsed != pType {
+			t.Fatalf("expected pipeline type %s but got %s", pType, parsed)
+		}
+	}
+
+	for _, s := range []string{"", "unknown", "Golang", "rust"} {
+		if pType, err := ParsePipelineType(s); err == nil || pType != PTypeUnknown {
+			t.Fatalf("expected error for pipeline type '%s' but got %s", s, pType)
+		}
+	}
+}
+
+func TestPipelineTypesCopy(t *testing.T) {
+	types := PipelineTypes()
+	types[0] = PTypeUnknown
+	if PipelineTypes()[0] == PTypeUnknown {
+		t.Fatal("modifying the returned pipeline types should not change the known types")
+	}
+}
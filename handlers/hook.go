@@ -122,7 +122,7 @@ func GitWebHook(c echo.Context) error {
 	}
 
 	var foundPipe *gaia.Pipeline
-	for _, pipe := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, pipe := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if pipe.Repo.URL == p.Repo.GitURL || pipe.Repo.URL == p.Repo.HTMLURL || pipe.Repo.URL == p.Repo.SSHURL {
 			foundPipe = &pipe
 			break
@@ -70,7 +70,7 @@ func TestHookReceive(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	p := gaia.Pipeline{
 		ID:      1,
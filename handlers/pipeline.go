@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,6 +18,10 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+// buildQueueRetryAfter is the number of seconds a client should wait
+// before it submits a build again which was rejected by a full build queue.
+const buildQueueRetryAfter = 30
+
 // PipelineGitLSRemote checks for available git remote branches.
 // This is the perfect way to check if we have access to a given repo.
 func PipelineGitLSRemote(c echo.Context) error {
@@ -44,15 +49,38 @@ func CreatePipeline(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	// Return the first build if the request has already been submitted.
+	// The key is claimed first because a retry would otherwise be rejected
+	// for the name which the first build is about to take.
+	p.ID = uuid.Must(uuid.NewV4(), nil).String()
+	if id, ok := pipeline.ClaimIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey, p.ID); !ok {
+		first, err := createPipelineByID(id)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, first)
+	}
+
 	// Validate pipeline name
 	if err := pipeline.ValidatePipelineName(p.Pipeline.Name); err != nil {
+		pipeline.ReleaseIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey)
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	// Check that the pipeline can be built on this server. Images are
+	// built by the container builder instead of the toolchain of the host.
+	canBuild := pipeline.CanBuild
+	if p.Image != nil {
+		canBuild = pipeline.CanBuildImage
+	}
+	if ok, err := canBuild(p.Pipeline.Type); !ok {
+		pipeline.ReleaseIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey)
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
 	// Set initial value
 	p.Created = time.Now()
 	p.StatusType = gaia.CreatePipelineRunning
-	p.ID = uuid.Must(uuid.NewV4(), nil).String()
 
 	// Add pipeline type tag if not already existent
 	if !stringhelper.IsContainedInSlice(p.Pipeline.Tags, p.Pipeline.Type.String(), true) {
@@ -62,16 +90,45 @@ func CreatePipeline(c echo.Context) error {
 	// Save this pipeline to our store
 	err := storeService.CreatePipelinePut(p)
 	if err != nil {
+		pipeline.ReleaseIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey)
 		gaia.Cfg.Logger.Debug("cannot put pipeline into store", "error", err.Error())
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
 	// Cloning the repo and compiling the pipeline will be done async
-	go pipeline.CreatePipeline(p)
+	// by the next free build worker
+	if err := pipeline.SubmitBuild(p); err != nil {
+		// Keep the rejected build visible instead of dropping it silently.
+		// The request can be retried with the same key.
+		pipeline.ReleaseIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey)
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = err.Error()
+		_ = storeService.CreatePipelinePut(p)
+		if err == pipeline.ErrBuildQueueFull {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(buildQueueRetryAfter))
+			return c.String(http.StatusServiceUnavailable, err.Error())
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
 
 	return c.JSON(http.StatusOK, nil)
 }
 
+// createPipelineByID returns the stored create pipeline with the given ID.
+func createPipelineByID(id string) (*gaia.CreatePipeline, error) {
+	storeService, _ := services.StorageService()
+	pipelineList, err := storeService.CreatePipelineGet()
+	if err != nil {
+		return nil, err
+	}
+	for i := range pipelineList {
+		if pipelineList[i].ID == id {
+			return &pipelineList[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot find build %s", id)
+}
+
 // CreatePipelineGetAll returns a json array of
 // all pipelines which are about to get compiled and
 // all pipelines which have been compiled.
@@ -102,7 +159,7 @@ func PipelineNameAvailable(c echo.Context) error {
 // PipelineGetAll returns all registered pipelines.
 func PipelineGetAll(c echo.Context) error {
 	// Get all active pipelines
-	pipelines := pipeline.GlobalActivePipelines.GetAll()
+	pipelines := pipeline.GetGlobalActivePipelines().GetAll()
 
 	// Return as json
 	return c.JSON(http.StatusOK, pipelines)
@@ -119,7 +176,7 @@ func PipelineGet(c echo.Context) error {
 	}
 
 	// Look up pipeline for the given id
-	for _, p := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, p := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if p.ID == pipelineID {
 			return c.JSON(http.StatusOK, p)
 		}
@@ -139,7 +196,7 @@ func PipelineUpdate(c echo.Context) error {
 
 	// Look up pipeline for the given id
 	var foundPipeline gaia.Pipeline
-	for _, pipe := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, pipe := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if pipe.ID == p.ID {
 			foundPipeline = pipe
 			break
@@ -152,27 +209,21 @@ func PipelineUpdate(c echo.Context) error {
 
 	// Check if the pipeline name was changed.
 	if foundPipeline.Name != p.Name {
-		// Pipeline name has been changed
-		currentName := foundPipeline.Name
-
-		// Rename binary
-		err := pipeline.RenameBinary(foundPipeline, p.Name)
+		// Rename the active pipeline and its binary
+		err := pipeline.GetGlobalActivePipelines().Rename(foundPipeline.Name, p.Name)
 		if err != nil {
 			return c.String(http.StatusInternalServerError, errPipelineRename.Error())
 		}
 
 		// Update name and exec path
 		foundPipeline.Name = p.Name
-		foundPipeline.ExecPath = pipeline.GetExecPath(p)
+		foundPipeline.ExecPath = pipeline.GetExecPath(foundPipeline)
 
 		// Update pipeline in store
 		err = storeService.PipelinePut(&foundPipeline)
 		if err != nil {
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
-
-		// Update active pipelines
-		pipeline.GlobalActivePipelines.ReplaceByName(currentName, foundPipeline)
 	}
 
 	// Check if the periodic scheduling has been changed.
@@ -212,7 +263,7 @@ func PipelineUpdate(c echo.Context) error {
 		foundPipeline.CronInst.Start()
 
 		// Update active pipelines
-		pipeline.GlobalActivePipelines.Replace(foundPipeline)
+		pipeline.GetGlobalActivePipelines().Replace(foundPipeline)
 	}
 
 	return c.String(http.StatusOK, "Pipeline has been updated")
@@ -247,7 +298,7 @@ func PipelineDelete(c echo.Context) error {
 	// Look up pipeline for the given id
 	var foundPipeline gaia.Pipeline
 	var deletedPipelineIndex int
-	for index, p := range pipeline.GlobalActivePipelines.GetAll() {
+	for index, p := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if p.ID == pipelineID {
 			foundPipeline = p
 			deletedPipelineIndex = index
@@ -272,7 +323,7 @@ func PipelineDelete(c echo.Context) error {
 	}
 
 	// Remove from active pipelines
-	if err := pipeline.GlobalActivePipelines.Remove(deletedPipelineIndex); err != nil {
+	if err := pipeline.GetGlobalActivePipelines().Remove(deletedPipelineIndex); err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
@@ -301,7 +352,7 @@ func PipelineTrigger(c echo.Context) error {
 
 	// Look up pipeline for the given id
 	var foundPipeline gaia.Pipeline
-	for _, p := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, p := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if p.ID == pipelineID {
 			foundPipeline = p
 			break
@@ -343,7 +394,7 @@ func PipelineResetToken(c echo.Context) error {
 
 	// Look up pipeline for the given id
 	var foundPipeline gaia.Pipeline
-	for _, p := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, p := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if p.ID == pipelineID {
 			foundPipeline = p
 			break
@@ -409,7 +460,7 @@ func PipelineStart(c echo.Context) error {
 
 	// Look up pipeline for the given id
 	var foundPipeline gaia.Pipeline
-	for _, p := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, p := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if p.ID == pipelineID {
 			foundPipeline = p
 			break
@@ -439,7 +490,7 @@ type getAllWithLatestRun struct {
 func PipelineGetAllWithLatestRun(c echo.Context) error {
 	// Get all active pipelines
 	storeService, _ := services.StorageService()
-	pipelines := pipeline.GlobalActivePipelines.GetAll()
+	pipelines := pipeline.GetGlobalActivePipelines().GetAll()
 
 	// Iterate all pipelines
 	var pipelinesWithLatestRun []getAllWithLatestRun
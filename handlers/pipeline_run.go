@@ -69,7 +69,7 @@ func PipelineStop(c echo.Context) error {
 
 	// Look up pipeline for the given id
 	var foundPipeline gaia.Pipeline
-	for _, pipe := range pipeline.GlobalActivePipelines.GetAll() {
+	for _, pipe := range pipeline.GetGlobalActivePipelines().GetAll() {
 		if pipe.ID == p {
 			foundPipeline = pipe
 			break
@@ -185,7 +185,7 @@ func TestPipelineUpdate(t *testing.T) {
 	defer func() { services.MockStorageService(nil) }()
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -330,7 +330,7 @@ func TestPipelineDelete(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -403,7 +403,7 @@ func TestPipelineStart(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -509,7 +509,7 @@ func TestPipelineRemoteTrigger(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -669,7 +669,7 @@ func TestPipelineResetToken(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -727,7 +727,7 @@ func TestPipelineCheckPeriodicSchedules(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -790,7 +790,7 @@ func TestPipelineNameAvailable(t *testing.T) {
 
 	// Initialize global active pipelines
 	ap := pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines = ap
+	pipeline.SetGlobalActivePipelines(ap)
 
 	// Initialize echo
 	e := echo.New()
@@ -878,3 +878,108 @@ func TestPipelineNameAvailable(t *testing.T) {
 		}
 	})
 }
+
+func TestCreatePipelineIdempotencyKeyRetry(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCreatePipelineIdempotencyKeyRetry")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = &gaia.Config{
+		Logger:       hclog.NewNullLogger(),
+		HomePath:     tmp,
+		DataPath:     tmp,
+		PipelinePath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	// Initialize global active pipelines
+	ap := pipeline.NewActivePipelines()
+	pipeline.SetGlobalActivePipelines(ap)
+
+	// The first request has been built and took the name
+	first := &gaia.CreatePipeline{ID: "first", IdempotencyKey: "retry-key", StatusType: gaia.CreatePipelineSuccess}
+	first.Pipeline.Name = "Pipeline A"
+	if err := dataStore.CreatePipelinePut(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pipeline.ClaimIdempotencyKey(first.Pipeline.Name, first.IdempotencyKey, first.ID); !ok {
+		t.Fatal("expected idempotency key to be claimed")
+	}
+	defer pipeline.ReleaseIdempotencyKey(first.Pipeline.Name, first.IdempotencyKey)
+	ap.Append(gaia.Pipeline{Name: "Pipeline A", Type: gaia.PTypeGolang, Created: time.Now()})
+
+	e := echo.New()
+	body, _ := json.Marshal(map[string]interface{}{
+		"idempotencykey": "retry-key",
+		"pipeline":       map[string]interface{}{"name": "Pipeline A", "type": "golang"},
+	})
+	req := httptest.NewRequest(echo.POST, "/", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	_ = CreatePipeline(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected response code %v got %v: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	result := &gaia.CreatePipeline{}
+	if err := json.Unmarshal(rec.Body.Bytes(), result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ID != first.ID {
+		t.Fatalf("expected the first build %s but got %s", first.ID, result.ID)
+	}
+}
+
+func TestCreatePipelineImageSkipsToolchainCheck(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCreatePipelineImageSkipsToolchainCheck")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = &gaia.Config{
+		Logger:       hclog.NewNullLogger(),
+		HomePath:     tmp,
+		DataPath:     tmp,
+		PipelinePath: tmp,
+	}
+	if _, err := services.StorageService(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+	active := pipeline.GetGlobalActivePipelines()
+	defer pipeline.SetGlobalActivePipelines(active)
+	pipeline.SetGlobalActivePipelines(pipeline.NewActivePipelines())
+
+	// Neither the java toolchain nor a container builder is installed
+	currentPath := os.Getenv("PATH")
+	_ = os.Setenv("PATH", tmp)
+	defer os.Setenv("PATH", currentPath)
+
+	createPipeline := func(request map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest(echo.POST, "/", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		_ = CreatePipeline(echo.New().NewContext(req, rec))
+		return rec
+	}
+
+	rec := createPipeline(map[string]interface{}{
+		"pipeline": map[string]interface{}{"name": "binary", "type": "java"},
+	})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "toolchain not installed") {
+		t.Fatalf("expected missing toolchain error but got %v: %s", rec.Code, rec.Body.String())
+	}
+
+	// Images are built by the container builder instead of the host toolchain
+	rec = createPipeline(map[string]interface{}{
+		"pipeline": map[string]interface{}{"name": "image", "type": "java"},
+		"image":    map[string]interface{}{"registry": "registry.example.com"},
+	})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "container builder") {
+		t.Fatalf("expected missing container builder error but got %v: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -7,9 +7,15 @@ import (
 
 const (
 	typeDelimiter = "_"
+
+	windowsExecutableExtension = ".exe"
+	wasmExtension              = ".wasm"
+	pluginModuleExtension      = ".so"
 )
 
-// GetRealPipelineName removes the suffix from the pipeline name.
+// GetRealPipelineName removes the suffix and the executable, WebAssembly
+// or plugin module extension from the pipeline name.
 func GetRealPipelineName(name string, pType gaia.PipelineType) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, windowsExecutableExtension), wasmExtension), pluginModuleExtension)
 	return strings.TrimSuffix(name, typeDelimiter+pType.String())
 }
\ No newline at end of file
@@ -11,4 +11,21 @@ func TestGetRealPipelineName(t *testing.T) {
 	if GetRealPipelineName(pipeGo, gaia.PTypeGolang) != "my_pipeline" {
 		t.Fatalf("output should be my_pipeline but is %s", GetRealPipelineName(pipeGo, gaia.PTypeGolang))
 	}
+
+	pipeWindows := "my_pipeline_golang.exe"
+	if GetRealPipelineName(pipeWindows, gaia.PTypeGolang) != "my_pipeline" {
+		t.Fatalf("output should be my_pipeline but is %s", GetRealPipelineName(pipeWindows, gaia.PTypeGolang))
+	}
+}
+
+func TestGetRealPipelineNameWasm(t *testing.T) {
+	if name := GetRealPipelineName("my_pipeline_golang.wasm", gaia.PTypeGolang); name != "my_pipeline" {
+		t.Fatalf("output should be my_pipeline but is %s", name)
+	}
+}
+
+func TestGetRealPipelineNamePluginModule(t *testing.T) {
+	if name := GetRealPipelineName("my_pipeline_golang.so", gaia.PTypeGolang); name != "my_pipeline" {
+		t.Fatalf("output should be my_pipeline but is %s", name)
+	}
 }
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/gaia-pipeline/gaia/workers/pipeline"
 
@@ -52,6 +54,22 @@ func init() {
 	fs.StringVar(&gaia.Cfg.Hostname, "hostname", "https://localhost", "The host's name under which Gaia is deployed at e.g.: https://gaia-pipeline.io")
 	fs.StringVar(&gaia.Cfg.VaultPath, "vault-path", "", "Path to the Gaia vault folder. By default, will be stored inside the home folder")
 	fs.IntVar(&gaia.Cfg.Worker, "concurrent-worker", 2, "Number of concurrent worker the Gaia instance will use to execute pipelines in parallel")
+	fs.DurationVar(&gaia.Cfg.BuildPrepareTimeout, "build-prepare-timeout", 20*time.Minute, "Max time the build will spend to fetch the dependencies of a pipeline")
+	fs.DurationVar(&gaia.Cfg.BuildCompileTimeout, "build-compile-timeout", 40*time.Minute, "Max time the build will spend to compile a pipeline")
+	fs.DurationVar(&gaia.Cfg.BuildIdempotencyWindow, "build-idempotency-window", 10*time.Minute, "Time a finished build is returned for further build requests with the same idempotency key")
+	fs.StringVar(&gaia.Cfg.ImageRegistry, "image-registry", "", "Default registry container images of pipelines are pushed to")
+	fs.StringVar(&gaia.Cfg.WasmRuntime, "wasm-runtime", "wasmtime", "Runtime which starts pipelines built as WebAssembly binaries")
+	fs.StringVar(&gaia.Cfg.SourceRoot, "source-root", "", "Folder which contains the local source directories pipelines can be built from. By default, local source directories are disabled")
+	fs.StringVar(&gaia.Cfg.GoLinter, "go-linter", "", "Linter which is run in addition to go vet for go pipelines with enabled static analysis, e.g. staticcheck")
+	fs.StringVar(&gaia.Cfg.Artifact.ModeRaw, "artifact-mode", "0700", "Octal file mode of installed pipeline binaries, e.g. 0750")
+	fs.StringVar(&gaia.Cfg.Artifact.Group, "artifact-group", "", "Group which owns the installed pipeline binaries. By default, the group is not changed")
+	fs.Int64Var(&gaia.Cfg.Artifact.MaxSize, "artifact-max-size", 1<<30, "Maximum size in bytes of installed pipeline binaries. Larger build results fail the build")
+	fs.StringVar(&gaia.Cfg.BuildProxy.HTTP, "build-http-proxy", "", "Proxy for HTTP requests of builds. By default, the HTTP_PROXY of the Gaia process is used")
+	fs.StringVar(&gaia.Cfg.BuildProxy.HTTPS, "build-https-proxy", "", "Proxy for HTTPS requests of builds. By default, the HTTPS_PROXY of the Gaia process is used")
+	fs.StringVar(&gaia.Cfg.BuildProxy.NoProxy, "build-no-proxy", "", "Comma separated list of hosts builds reach without proxy. By default, the NO_PROXY of the Gaia process is used")
+	fs.IntVar(&gaia.Cfg.BuildHistorySize, "build-history-size", 20, "Number of builds which are kept in the build history of each pipeline")
+	fs.IntVar(&gaia.Cfg.BuildWorker, "concurrent-build-worker", 2, "Number of concurrent worker the Gaia instance will use to build pipelines in parallel")
+	fs.IntVar(&gaia.Cfg.BuildQueueSize, "build-queue-size", 100, "Maximum number of builds which wait for a free build worker. Further builds are rejected until the queue drains")
 	fs.StringVar(&gaia.Cfg.JwtPrivateKeyPath, "jwt-private-key-path", "", "A RSA private key used to sign JWT tokens used for Web UI authentication")
 	fs.StringVar(&gaia.Cfg.CAPath, "ca-path", "", "Path where the generated CA certificate files will be saved")
 	fs.BoolVar(&gaia.Cfg.DevMode, "dev", false, "If true, Gaia will be started in development mode. Don't use this in production!")
@@ -66,6 +84,7 @@ func init() {
 	fs.StringVar(&gaia.Cfg.WorkerServerPort, "worker-server-port", "8989", "Listen port for Gaia primary worker gRPC communication. Only used in server mode")
 	fs.StringVar(&gaia.Cfg.WorkerTags, "worker-tags", "", "Comma separated list of custom tags for this worker. Only used in worker mode")
 	fs.BoolVar(&gaia.Cfg.PreventPrimaryWork, "prevent-primary-work", false, "If true, prevents the scheduler to schedule work on this Gaia primary instance. Only used in server mode")
+	fs.StringVar((*string)(&gaia.Cfg.CleanupPolicy), "build-cleanup-policy", string(gaia.CleanupOnSuccess), "Defines when the temporary build folder is removed. Possible options are always, onsuccess and never")
 
 	// Default values
 	gaia.Cfg.Bolt.Mode = 0600
@@ -104,6 +123,16 @@ func Start() (err error) {
 		return errors.New("unsupported mode used")
 	}
 
+	// Parse the file mode of the installed pipeline artifacts
+	if gaia.Cfg.Artifact.ModeRaw != "" {
+		mode, err := strconv.ParseUint(gaia.Cfg.Artifact.ModeRaw, 8, 32)
+		if err != nil || mode > 0777 {
+			gaia.Cfg.Logger.Error("invalid artifact mode used", "mode", gaia.Cfg.Artifact.ModeRaw)
+			return errors.New("invalid artifact mode used")
+		}
+		gaia.Cfg.Artifact.Mode = os.FileMode(mode)
+	}
+
 	// Find path for gaia home folder if not given by parameter
 	if gaia.Cfg.HomePath == "" {
 		// Find executable path
@@ -557,6 +557,7 @@ func (a *Agent) rebuildWorkerBinary(ctx context.Context, pipeline *gaia.Pipeline
 	pCreate := &gaia.CreatePipeline{}
 	pCreate.ID = security.GenerateRandomUUIDV5()
 	pCreate.Pipeline = *pipeline
+	pCreate.PathFilters = pipeline.PathFilters
 
 	repo, err := a.client.GetGitRepo(ctx, &pb.PipelineID{Id: int64(pipeline.ID)})
 	if err != nil {
@@ -10,11 +10,14 @@ import (
 
 var (
 	supportedBinaries = map[gaia.PipelineType]string{
-		gaia.PTypePython: "python",
-		gaia.PTypeJava:   "mvn",
-		gaia.PTypeCpp:    "make",
-		gaia.PTypeGolang: "go",
-		gaia.PTypeRuby:   "gem",
+		gaia.PTypePython:  "python",
+		gaia.PTypeJava:    "mvn",
+		gaia.PTypeCpp:     "make",
+		gaia.PTypeGolang:  "go",
+		gaia.PTypeRuby:    "gem",
+		gaia.PTypePerl:    "perl",
+		gaia.PTypeR:       "Rscript",
+		gaia.PTypeHaskell: "ghc",
 	}
 )
 
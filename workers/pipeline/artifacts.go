@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// artifactsSuffix is the suffix of the folder next to the pipeline binary
+// which holds the additional artifacts of the pipeline.
+const artifactsSuffix = ".artifacts"
+
+// validateArtifacts checks the artifact patterns of the given pipeline.
+// Patterns must be valid and stay inside of the source tree.
+func validateArtifacts(p *gaia.CreatePipeline) error {
+	if len(p.Artifacts) > 0 && p.Image != nil {
+		return fmt.Errorf("artifacts are not supported for container images")
+	}
+	for _, pattern := range p.Artifacts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid artifact pattern %s: %s", pattern, err.Error())
+		}
+		clean := path.Clean(pattern)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("artifact pattern %s must be relative to the source root", pattern)
+		}
+	}
+	return nil
+}
+
+// findArtifacts returns the files in the given source tree which match the
+// artifact patterns, relative to the source root. A pattern which matches a
+// folder matches all files inside of the folder. Every pattern must match
+// at least one file.
+func findArtifacts(root string, patterns []string) ([]string, error) {
+	found := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, err
+		}
+		count := 0
+		for _, match := range matches {
+			err = filepath.Walk(match, func(name string, info os.FileInfo, err error) error {
+				if err != nil || !info.Mode().IsRegular() {
+					return err
+				}
+				rel, err := filepath.Rel(root, name)
+				if err != nil {
+					return err
+				}
+				found[rel] = true
+				count++
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("cannot find artifact %s", pattern)
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for file := range found {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// installArtifacts copies the given files from the source tree into the
+// artifacts folder of the given binary. Artifacts of a previous build are
+// removed first.
+func installArtifacts(root string, files []string, binary string) error {
+	dest := binary + artifactsSuffix
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	for _, file := range files {
+		src := filepath.Join(root, file)
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, file)
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return err
+		}
+		if err := copyFileContents(src, target); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
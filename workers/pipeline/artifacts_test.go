@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestValidateArtifacts(t *testing.T) {
+	for _, pattern := range []string{"[a-", "/etc/passwd", "../secret", "templates/../../secret"} {
+		if err := validateArtifacts(&gaia.CreatePipeline{Artifacts: []string{pattern}}); err == nil {
+			t.Fatalf("expected artifact pattern %s to be rejected", pattern)
+		}
+	}
+	p := &gaia.CreatePipeline{Artifacts: []string{"templates/*.tmpl"}, Image: &gaia.ImageOptions{}}
+	if err := validateArtifacts(p); err == nil || !strings.Contains(err.Error(), "container images") {
+		t.Fatalf("expected image error but got %v", err)
+	}
+	p.Image = nil
+	if err := validateArtifacts(p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindAndInstallArtifacts(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestFindAndInstallArtifacts")
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "src")
+	_ = os.MkdirAll(filepath.Join(src, "templates", "mail"), 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, "config.yaml"), []byte("config"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(src, "templates", "index.tmpl"), []byte("index"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(src, "templates", "mail", "welcome.tmpl"), []byte("welcome"), 0600)
+
+	files, err := findArtifacts(src, []string{"*.yaml", "templates", "templates/*.tmpl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"config.yaml", filepath.Join("templates", "index.tmpl"), filepath.Join("templates", "mail", "welcome.tmpl")}
+	if strings.Join(files, ":") != strings.Join(expected, ":") {
+		t.Fatalf("expected artifacts %v but got %v", expected, files)
+	}
+
+	// Declared artifacts which do not exist fail the build
+	if _, err := findArtifacts(src, []string{"config.yaml", "missing.txt"}); err == nil || !strings.Contains(err.Error(), "cannot find artifact missing.txt") {
+		t.Fatalf("expected missing artifact error but got %v", err)
+	}
+
+	binary := filepath.Join(tmp, "pipeline_golang")
+	_ = os.MkdirAll(binary+artifactsSuffix, 0700)
+	_ = ioutil.WriteFile(filepath.Join(binary+artifactsSuffix, "stale"), []byte("stale"), 0600)
+	if err := installArtifacts(src, files, binary); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(binary+artifactsSuffix, "templates", "mail", "welcome.tmpl"))
+	if err != nil || string(content) != "welcome" {
+		t.Fatalf("expected copied artifact. error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(binary+artifactsSuffix, "stale")); !os.IsNotExist(err) {
+		t.Fatal("expected artifacts of the previous build to be removed")
+	}
+}
+
+func TestRenameAndDeleteBinaryArtifacts(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestRenameAndDeleteBinaryArtifacts")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.HomePath = tmp
+
+	p := gaia.Pipeline{
+		Name:    "PipelineA",
+		Type:    gaia.PTypeGolang,
+		Created: time.Now(),
+	}
+	binary := filepath.Join(tmp, appendTypeToName(p.Name, p.Type))
+	_ = ioutil.WriteFile(binary, []byte("binary"), 0600)
+	_ = os.MkdirAll(binary+artifactsSuffix, 0700)
+	_ = ioutil.WriteFile(filepath.Join(binary+artifactsSuffix, "config.yaml"), []byte("config"), 0600)
+
+	if err := RenameBinary(p, "PipelineB"); err != nil {
+		t.Fatal(err)
+	}
+	p.Name = "PipelineB"
+	renamed := filepath.Join(tmp, appendTypeToName(p.Name, p.Type)) + artifactsSuffix
+	if _, err := os.Stat(filepath.Join(renamed, "config.yaml")); err != nil {
+		t.Fatalf("expected artifacts to be renamed: %s", err)
+	}
+
+	if err := DeleteBinary(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(renamed); !os.IsNotExist(err) {
+		t.Fatal("expected artifacts to be deleted")
+	}
+}
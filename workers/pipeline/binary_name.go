@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+)
+
+// binaryMetadataSuffix is the suffix of the metadata file which is
+// stored next to every pipeline binary.
+const binaryMetadataSuffix = ".meta.json"
+
+// BinaryNameFunc computes the file name of a pipeline binary
+// from the pipeline name and type.
+type BinaryNameFunc func(name string, pType gaia.PipelineType) string
+
+var (
+	// binaryNameFunc is the currently used naming function.
+	binaryNameFunc BinaryNameFunc = defaultBinaryName
+
+	// binaryNameFuncLock protects the naming function.
+	binaryNameFuncLock sync.RWMutex
+)
+
+// binaryMetadata is the content of the metadata file of a pipeline binary.
+type binaryMetadata struct {
+	Name         string            `json:"name"`
+	Type         gaia.PipelineType `json:"type"`
+	PluginModule bool              `json:"pluginmodule,omitempty"`
+}
+
+// SetBinaryNameFunc sets the function which computes the file names of
+// pipeline binaries. The default scheme "<name>_<type>" is restored if f
+// is nil. The executable extension required by windows is always added.
+// Name and type of the pipeline are stored in a metadata file next to the
+// binary because custom names cannot be parsed.
+func SetBinaryNameFunc(f BinaryNameFunc) {
+	binaryNameFuncLock.Lock()
+	defer binaryNameFuncLock.Unlock()
+
+	if f == nil {
+		f = defaultBinaryName
+	}
+	binaryNameFunc = f
+}
+
+// defaultBinaryName returns the file name used by the default scheme.
+func defaultBinaryName(n string, pType gaia.PipelineType) string {
+	return fmt.Sprintf("%s%s%s", n, typeDelimiter, pType.String())
+}
+
+// binaryName returns the file name of a pipeline binary computed by
+// the current naming function.
+func binaryName(n string, pType gaia.PipelineType) string {
+	binaryNameFuncLock.RLock()
+	defer binaryNameFuncLock.RUnlock()
+	return binaryNameFunc(n, pType)
+}
+
+// isBinaryMetadata checks if the given file name is a metadata file.
+func isBinaryMetadata(fileName string) bool {
+	return strings.HasSuffix(fileName, binaryMetadataSuffix)
+}
+
+// writeBinaryMetadata writes the metadata file for the pipeline binary at execPath.
+func writeBinaryMetadata(execPath string, p gaia.Pipeline) error {
+	data, err := json.Marshal(binaryMetadata{Name: p.Name, Type: p.Type, PluginModule: p.PluginModule})
+	if err != nil {
+		return err
+	}
+	return writeArtifact(execPath+binaryMetadataSuffix, func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	})
+}
+
+// parseBinaryName returns the pipeline name and type of the binary with the
+// given file name in the given folder. The metadata file is used if it
+// exists. Otherwise, name and type are parsed from the default scheme.
+func parseBinaryName(folder, fileName string) (string, gaia.PipelineType, error) {
+	data, err := ioutil.ReadFile(filepath.Join(folder, fileName+binaryMetadataSuffix))
+	switch {
+	case err == nil:
+		m := binaryMetadata{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return "", gaia.PTypeUnknown, fmt.Errorf("cannot read metadata of pipeline %s: %s", fileName, err.Error())
+		}
+		return m.Name, m.Type, nil
+	case !os.IsNotExist(err):
+		return "", gaia.PTypeUnknown, err
+	}
+
+	pType, err := getPipelineType(fileName)
+	if err != nil {
+		return "", gaia.PTypeUnknown, err
+	}
+	return pipelinehelper.GetRealPipelineName(fileName, pType), pType, nil
+}
+
+// isPluginModule checks if the metadata file of the binary with the given
+// file name in the given folder flags it as go plugin module.
+func isPluginModule(folder, fileName string) bool {
+	data, err := ioutil.ReadFile(filepath.Join(folder, fileName+binaryMetadataSuffix))
+	if err != nil {
+		return false
+	}
+	m := binaryMetadata{}
+	return json.Unmarshal(data, &m) == nil && m.PluginModule
+}
@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+)
+
+func TestSetBinaryNameFunc(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestSetBinaryNameFunc")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+
+	SetBinaryNameFunc(func(n string, pType gaia.PipelineType) string {
+		return "pipeline-" + n
+	})
+	defer SetBinaryNameFunc(nil)
+
+	p := gaia.Pipeline{Name: "my_pipeline", Type: gaia.PTypePython}
+	fileName := appendTypeToName(p.Name, p.Type)
+	if fileName != "pipeline-my_pipeline" {
+		t.Fatalf("expected custom binary name but got %s", fileName)
+	}
+
+	// Name and type are read from the metadata file
+	execPath := filepath.Join(tmp, fileName)
+	if err := ioutil.WriteFile(execPath, []byte("content"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBinaryMetadata(execPath, p); err != nil {
+		t.Fatal(err)
+	}
+	name, pType, err := parseBinaryName(tmp, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != p.Name || pType != p.Type {
+		t.Fatalf("expected %s/%s but got %s/%s", p.Name, p.Type, name, pType)
+	}
+
+	// Renaming keeps the metadata in sync
+	if err := RenameBinary(p, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	name, _, err = parseBinaryName(tmp, "pipeline-renamed")
+	if err != nil || name != "renamed" {
+		t.Fatalf("expected renamed pipeline but got %s: %v", name, err)
+	}
+
+	// Deleting removes the metadata file as well
+	p.Name = "renamed"
+	if err := DeleteBinary(p); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := ioutil.ReadDir(tmp)
+	if len(files) != 0 {
+		t.Fatalf("expected empty pipeline folder but got %d files", len(files))
+	}
+}
+
+func TestParseBinaryNameDefault(t *testing.T) {
+	SetBinaryNameFunc(nil)
+	if n := appendTypeToName("my_pipeline", gaia.PTypeGolang); n != "my_pipeline_golang" && n != "my_pipeline_golang.exe" {
+		t.Fatalf("unexpected default binary name %s", n)
+	}
+
+	// Without metadata file the default scheme is parsed
+	name, pType, err := parseBinaryName(os.TempDir(), "my_pipeline_golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "my_pipeline" || pType != gaia.PTypeGolang {
+		t.Fatalf("expected my_pipeline/golang but got %s/%s", name, pType)
+	}
+	if _, _, err := parseBinaryName(os.TempDir(), "invalid"); err == nil {
+		t.Fatal("expected error for binary name without type")
+	}
+	if !isBinaryMetadata("my_pipeline_golang" + binaryMetadataSuffix) {
+		t.Fatal("expected metadata file to be detected")
+	}
+}
+
+func FuzzBinaryNameRoundTrip(f *testing.F) {
+	SetBinaryNameFunc(nil)
+	f.Add("my_pipeline", uint8(0), "linux")
+	f.Add("pipeline_golang", uint8(1), "windows")
+	f.Add("_", uint8(2), "js")
+	f.Add("ünïcödé pipeline", uint8(3), "linux")
+	f.Add("pipeline.exe", uint8(0), "windows")
+	f.Add("", uint8(0), "linux")
+	f.Fuzz(func(t *testing.T, name string, typeIndex uint8, goos string) {
+		types := gaia.PipelineTypes()
+		pType := types[int(typeIndex)%len(types)]
+		fileName := appendTypeToNameForOS(name, pType, goos)
+
+		parsed, err := getPipelineType(fileName)
+		if name == "" {
+			if err == nil {
+				t.Fatalf("expected file name %q without pipeline name to be rejected", fileName)
+			}
+			return
+		}
+		if err != nil || parsed != pType {
+			t.Fatalf("expected pipeline type %s for %q but got %s: %v", pType, fileName, parsed, err)
+		}
+		if realName := pipelinehelper.GetRealPipelineName(fileName, pType); realName != name {
+			t.Fatalf("expected pipeline name %q for %q but got %q", name, fileName, realName)
+		}
+	})
+}
+
+func FuzzGetPipelineType(f *testing.F) {
+	SetBinaryNameFunc(nil)
+	f.Add("my_pipeline_golang")
+	f.Add("_golang")
+	f.Add("golang")
+	f.Add("my_pipeline_golang.wasm.exe")
+	f.Add("my_pipeline_Golang")
+	f.Fuzz(func(t *testing.T, fileName string) {
+		pType, err := getPipelineType(fileName)
+		if err != nil {
+			return
+		}
+
+		// A parsed file name must be the encoding of the parsed name and type
+		name := pipelinehelper.GetRealPipelineName(fileName, pType)
+		if name == "" {
+			t.Fatalf("expected file name %q without pipeline name to be rejected", fileName)
+		}
+		trimmed := strings.TrimSuffix(strings.TrimSuffix(fileName, windowsExecutableExtension), wasmExtension)
+		if encoded := binaryName(name, pType); encoded != trimmed {
+			t.Fatalf("file name %q was parsed as %q/%s which encodes to %q", fileName, name, pType, encoded)
+		}
+	})
+}
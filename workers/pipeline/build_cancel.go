@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+)
+
+var (
+	// ErrBuildNotFound is returned when a build should be cancelled but
+	// the pipeline has neither a pending nor a running build.
+	ErrBuildNotFound = errors.New("no pending or running build found for pipeline")
+
+	// errBuildCancelled is the result of a cancelled build.
+	errBuildCancelled = errors.New("build has been cancelled")
+
+	// cancelableBuilds are the contexts of all builds started by the build queue.
+	cancelableBuilds = map[*gaia.CreatePipeline]*cancelableBuild{}
+
+	// cancelableBuildsLock protects the cancelable builds.
+	cancelableBuildsLock sync.Mutex
+)
+
+// cancelableBuild is the context of a running build. Name and ID are recorded
+// when the build starts because the build changes its pipeline.
+type cancelableBuild struct {
+	name   string
+	id     string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// startBuildContext creates the context of the given build.
+func startBuildContext(p *gaia.CreatePipeline) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancelableBuildsLock.Lock()
+	defer cancelableBuildsLock.Unlock()
+	cancelableBuilds[p] = &cancelableBuild{name: p.Pipeline.Name, id: p.ID, ctx: ctx, cancel: cancel}
+}
+
+// finishBuildContext releases the context of the given build.
+func finishBuildContext(p *gaia.CreatePipeline) {
+	cancelableBuildsLock.Lock()
+	defer cancelableBuildsLock.Unlock()
+
+	if b, ok := cancelableBuilds[p]; ok {
+		b.cancel()
+		delete(cancelableBuilds, p)
+	}
+}
+
+// buildContext returns the context of the given build. Builds which have
+// not been started by the build queue are never cancelled.
+func buildContext(p *gaia.CreatePipeline) context.Context {
+	cancelableBuildsLock.Lock()
+	defer cancelableBuildsLock.Unlock()
+
+	if b, ok := cancelableBuilds[p]; ok {
+		return b.ctx
+	}
+
+	// Entries of the build matrix are copies of the build with the same ID
+	if p.ID != "" {
+		for _, b := range cancelableBuilds {
+			if b.id == p.ID {
+				return b.ctx
+			}
+		}
+	}
+	return context.Background()
+}
+
+// CancelBuild cancels the build of the pipeline with the given name. Pending
+// builds are removed from the build queue. If there is no pending build the
+// running build is cancelled. ErrBuildNotFound is returned if there is
+// neither a pending nor a running build.
+func CancelBuild(name string) error {
+	if removed := globalBuildQueue.remove(name); len(removed) > 0 {
+		// Keep the cancelled builds visible instead of dropping them silently
+		storeService, _ := services.StorageService()
+		for _, p := range removed {
+			ReleaseIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey)
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = errBuildCancelled.Error()
+			_ = storeService.CreatePipelinePut(p)
+		}
+		return nil
+	}
+
+	cancelableBuildsLock.Lock()
+	defer cancelableBuildsLock.Unlock()
+
+	found := false
+	for _, b := range cancelableBuilds {
+		if b.name == name {
+			b.cancel()
+			found = true
+		}
+	}
+	if !found {
+		return ErrBuildNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestCancelBuild(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildWorker = 1
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	services.MockStorageService(&mockCreatePipelineStore{})
+	defer services.MockStorageService(nil)
+
+	// Running builds wait until they get cancelled
+	started := make(chan string)
+	cancelled := make(chan string)
+	q := newBuildQueue(func(p *gaia.CreatePipeline) {
+		started <- p.Pipeline.Name
+		<-buildContext(p).Done()
+		cancelled <- p.Pipeline.Name
+	})
+	current := globalBuildQueue
+	globalBuildQueue = q
+	defer func() { globalBuildQueue = current }()
+
+	running := new(gaia.CreatePipeline)
+	running.Pipeline.Name = "running"
+	_ = q.submit(running)
+	<-started
+	pending := new(gaia.CreatePipeline)
+	pending.Pipeline.Name = "pending"
+	_ = q.submit(pending)
+
+	// Pending builds are removed from the queue
+	if err := CancelBuild("pending"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := q.position("pending"); ok {
+		t.Fatal("cancelled build should not be queued")
+	}
+	if pending.StatusType != gaia.CreatePipelineFailed || pending.Output != errBuildCancelled.Error() {
+		t.Fatalf("expected cancelled build to be failed but got %s: %s", pending.StatusType, pending.Output)
+	}
+
+	// Running builds are cancelled by their context
+	if err := CancelBuild("running"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case name := <-cancelled:
+		if name != "running" {
+			t.Fatalf("expected build 'running' to be cancelled but got '%s'", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("running build has not been cancelled")
+	}
+
+	if err := CancelBuild("unknown"); err != ErrBuildNotFound {
+		t.Fatalf("expected error '%v' but got '%v'", ErrBuildNotFound, err)
+	}
+}
+
+func TestRunCmdCancelled(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	output, err := runCmd(ctx, "", nil, phaseCompile, "/bin/sleep", []string{"10"}, nil, "")
+	if err != errBuildCancelled {
+		t.Fatalf("expected error '%v' but got '%v'", errBuildCancelled, err)
+	}
+	if string(output) != "\n"+errBuildCancelled.Error() {
+		t.Fatalf("expected cancellation in output but got '%s'", string(output))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
@@ -24,6 +23,10 @@ type BuildPipelineCpp struct {
 
 // PrepareEnvironment prepares the environment before we start the build process.
 func (b *BuildPipelineCpp) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// create uniqueName for destination folder
 	uniqueName := uuid.Must(uuid.NewV4(), nil)
 
@@ -31,7 +34,7 @@ func (b *BuildPipelineCpp) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	cloneFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpCppFolder, srcFolder, uniqueName.String())
 	err := os.MkdirAll(cloneFolder, 0700)
 	if err != nil {
-		return err
+		return prepareFailed(err)
 	}
 
 	// Set new generated path in pipeline obj for later usage
@@ -43,13 +46,22 @@ func (b *BuildPipelineCpp) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	return nil
 }
 
+// CheckEnvironment checks if the C++ toolchain is installed.
+func (b *BuildPipelineCpp) CheckEnvironment() error {
+	return checkToolchain("C++", cppBinaryName)
+}
+
 // ExecuteBuild executes the c++ build process
 func (b *BuildPipelineCpp) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Look for c++ binary executable
 	path, err := exec.LookPath(cppBinaryName)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot find c++ binary executable", "error", err.Error())
-		return err
+		return compileFailed(err)
 	}
 
 	// Set command args for build
@@ -61,12 +73,19 @@ func (b *BuildPipelineCpp) ExecuteBuild(p *gaia.CreatePipeline) error {
 		localDest = p.Pipeline.Repo.LocalDest
 	}
 
+	// Set build environment
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
 	// Execute and wait until finish or timeout
-	output, err := executeCmd(path, args, os.Environ(), localDest)
+	output, err := executeBuildCmd(p, phaseCompile, path, args, env, localDest)
 	p.Output = string(output)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot build pipeline", "error", err.Error(), "output", string(output))
-		return err
+		return compileFailed(err)
 	}
 
 	// Build has been finished. Set execution path to the build result archive.
@@ -79,17 +98,16 @@ func (b *BuildPipelineCpp) ExecuteBuild(p *gaia.CreatePipeline) error {
 // CopyBinary copies the final compiled binary to the
 // destination folder.
 func (b *BuildPipelineCpp) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Define src and destination
 	src := filepath.Join(p.Pipeline.Repo.LocalDest, cppFinalBinaryName)
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-
-	// Copy binary
-	if err := copyFileContents(src, dest); err != nil {
-		return err
-	}
+	dest := binaryDestination(p)
 
-	// Set +x (execution right) for pipeline
-	return os.Chmod(dest, gaia.ExecutablePermission)
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
 }
 
 // SavePipeline saves the current pipeline configuration.
@@ -97,7 +115,7 @@ func (b *BuildPipelineCpp) SavePipeline(p *gaia.Pipeline) error {
 	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
 	p.ExecPath = dest
 	p.Type = gaia.PTypeCpp
-	p.Name = strings.TrimSuffix(filepath.Base(dest), typeDelimiter+gaia.PTypeCpp.String())
+	p.Name = filepath.Base(p.Name)
 	p.Created = time.Now()
 	// Our pipeline is finished constructing. Save it.
 	storeService, _ := services.StorageService()
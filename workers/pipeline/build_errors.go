@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"errors"
+)
+
+// The build phase errors wrap the cause of a failed build step so callers
+// can tell the steps apart with errors.As. The message is the one of the cause.
+
+// ErrPrepareFailed is returned if the environment of a build
+// could not be prepared or the sources could not be fetched.
+type ErrPrepareFailed struct {
+	Err error
+}
+
+func (e *ErrPrepareFailed) Error() string { return e.Err.Error() }
+
+// Unwrap returns the cause of the failure.
+func (e *ErrPrepareFailed) Unwrap() error { return e.Err }
+
+// ErrCompileFailed is returned if the pipeline could not be built.
+type ErrCompileFailed struct {
+	Err error
+}
+
+func (e *ErrCompileFailed) Error() string { return e.Err.Error() }
+
+// Unwrap returns the cause of the failure.
+func (e *ErrCompileFailed) Unwrap() error { return e.Err }
+
+// ErrCopyFailed is returned if the build result could not be
+// copied to the pipeline folder or pushed to the registry.
+type ErrCopyFailed struct {
+	Err error
+}
+
+func (e *ErrCopyFailed) Error() string { return e.Err.Error() }
+
+// Unwrap returns the cause of the failure.
+func (e *ErrCopyFailed) Unwrap() error { return e.Err }
+
+// isPhaseError checks if the given error is already one of the
+// build phase errors. Those are never wrapped twice.
+func isPhaseError(err error) bool {
+	var prepare *ErrPrepareFailed
+	var compile *ErrCompileFailed
+	var cp *ErrCopyFailed
+	return errors.As(err, &prepare) || errors.As(err, &compile) || errors.As(err, &cp)
+}
+
+// prepareFailed wraps the given error into ErrPrepareFailed.
+func prepareFailed(err error) error {
+	if err == nil || isPhaseError(err) {
+		return err
+	}
+	return &ErrPrepareFailed{Err: err}
+}
+
+// compileFailed wraps the given error into ErrCompileFailed.
+func compileFailed(err error) error {
+	if err == nil || isPhaseError(err) {
+		return err
+	}
+	return &ErrCompileFailed{Err: err}
+}
+
+// copyFailed wraps the given error into ErrCopyFailed.
+func copyFailed(err error) error {
+	if err == nil || isPhaseError(err) {
+		return err
+	}
+	return &ErrCopyFailed{Err: err}
+}
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestPhaseErrorsAreNotWrappedTwice(t *testing.T) {
+	cause := errors.New("cause")
+	err := copyFailed(compileFailed(cause))
+
+	var compile *ErrCompileFailed
+	if !errors.As(err, &compile) {
+		t.Fatalf("expected compile failure but got %T", err)
+	}
+	var cp *ErrCopyFailed
+	if errors.As(err, &cp) {
+		t.Fatal("compile failure has been wrapped into a copy failure")
+	}
+	if !errors.Is(err, cause) {
+		t.Fatal("cause is not reachable")
+	}
+	if err.Error() != "cause" {
+		t.Fatalf("expected message of the cause but got %q", err.Error())
+	}
+	if prepareFailed(nil) != nil || compileFailed(nil) != nil || copyFailed(nil) != nil {
+		t.Fatal("nil errors must not be wrapped")
+	}
+}
+
+func TestBuildStepsReturnPhaseErrors(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestBuildStepsReturnPhaseErrors")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	b := new(BuildPipelineGolang)
+
+	// A file as home path makes creating the build folder fail
+	home := filepath.Join(tmp, "home")
+	_ = ioutil.WriteFile(home, nil, 0600)
+	gaia.Cfg.HomePath = home
+	var prepare *ErrPrepareFailed
+	if err := b.PrepareEnvironment(new(gaia.CreatePipeline)); !errors.As(err, &prepare) {
+		t.Fatalf("expected prepare failure but got %v", err)
+	}
+
+	gaia.Cfg.HomePath = tmp
+	currentPath := os.Getenv("PATH")
+	defer func() { _ = os.Setenv("PATH", currentPath) }()
+	_ = os.Setenv("PATH", "")
+	var compile *ErrCompileFailed
+	if err := b.ExecuteBuild(new(gaia.CreatePipeline)); !errors.As(err, &compile) {
+		t.Fatalf("expected compile failure but got %v", err)
+	}
+
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: "/noneexistent"}
+	var cp *ErrCopyFailed
+	if err := b.CopyBinary(p); !errors.As(err, &cp) {
+		t.Fatalf("expected copy failure but got %v", err)
+	}
+}
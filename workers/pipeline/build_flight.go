@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// buildFlight is a build which is shared by all identical builds
+// which have been requested while it is in progress.
+type buildFlight struct {
+	key string
+
+	// done is closed when the build has been finished.
+	done chan struct{}
+
+	// result is the finished build. It must not be read before done is closed.
+	result gaia.CreatePipeline
+}
+
+var (
+	// buildFlights maps the keys of the builds in progress to their flights.
+	buildFlights = map[string]*buildFlight{}
+
+	// buildFlightsLock protects the build flights.
+	buildFlightsLock sync.Mutex
+)
+
+// buildFlightKey returns the key of the build of the given pipeline from
+// the given sources. Builds are only identical if all options of the build
+// requests match.
+func buildFlightKey(p *gaia.CreatePipeline, sourceHash string) (string, error) {
+	spec, err := buildSpec(p)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{p.Pipeline.Name, sourceHash, spec}, "\x00"), nil
+}
+
+// joinBuildFlight returns the flight of the build in progress with the
+// given key together with false. A new flight is started and true is
+// returned if there is none. The caller must finish the new flight.
+func joinBuildFlight(key string) (*buildFlight, bool) {
+	buildFlightsLock.Lock()
+	defer buildFlightsLock.Unlock()
+
+	if f, ok := buildFlights[key]; ok {
+		return f, false
+	}
+	f := &buildFlight{key: key, done: make(chan struct{})}
+	buildFlights[key] = f
+	return f, true
+}
+
+// finish stores the result of the given finished build and hands it
+// to all builds which joined the flight.
+func (f *buildFlight) finish(p *gaia.CreatePipeline) {
+	buildFlightsLock.Lock()
+	defer buildFlightsLock.Unlock()
+
+	f.result = *p
+	delete(buildFlights, f.key)
+	close(f.done)
+}
+
+// wait blocks until the flight has been finished and copies its result
+// into the given build.
+func (f *buildFlight) wait(p *gaia.CreatePipeline) {
+	<-f.done
+	p.Status = f.result.Status
+	p.StatusType = f.result.StatusType
+	p.Output = f.result.Output
+	p.MatrixResults = f.result.MatrixResults
+}
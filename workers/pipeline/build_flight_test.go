@@ -0,0 +1,176 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestBuildFlightKey(t *testing.T) {
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Repo = &gaia.GitRepo{URL: "https://github.com/gaia-pipeline/pipeline-test", LocalDest: "/tmp/first"}
+	flightKey := func(p *gaia.CreatePipeline, sourceHash string) string {
+		key, err := buildFlightKey(p, sourceHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	key := flightKey(p, "hash")
+	if flightKey(p, "other") == key {
+		t.Fatal("expected different sources to have different keys")
+	}
+
+	// Retries of a request share the build although they are built in another folder
+	retry := *p
+	retry.ID = "retry"
+	retry.IdempotencyKey = "retry"
+	retry.Pipeline.Repo = &gaia.GitRepo{URL: p.Pipeline.Repo.URL, LocalDest: "/tmp/retry"}
+	if flightKey(&retry, "hash") != key {
+		t.Fatal("expected retried build to have the same key")
+	}
+
+	// Options which are not build flags are part of the key as well
+	for _, change := range []func(p *gaia.CreatePipeline){
+		func(p *gaia.CreatePipeline) { p.Lint = true },
+		func(p *gaia.CreatePipeline) { p.Matrix = []gaia.BuildTarget{{OS: "linux", Arch: "arm64"}} },
+		func(p *gaia.CreatePipeline) { p.SmokeTestFlag = "--version" },
+		func(p *gaia.CreatePipeline) { p.PreserveSource = true },
+		func(p *gaia.CreatePipeline) { p.Pipeline.DefaultArgs = []*gaia.Argument{{Key: "env"}} },
+	} {
+		changed := *p
+		change(&changed)
+		if flightKey(&changed, "hash") == key {
+			t.Fatalf("expected different build options to have different keys: %+v", changed)
+		}
+	}
+}
+
+func TestBuildFlight(t *testing.T) {
+	leader, ok := joinBuildFlight("main\x00hash")
+	if !ok {
+		t.Fatal("expected first build to start the flight")
+	}
+
+	// Identical builds wait for the result of the first build
+	results := make(chan *gaia.CreatePipeline)
+	for i := 0; i < 2; i++ {
+		f, ok := joinBuildFlight("main\x00hash")
+		if ok || f != leader {
+			t.Fatal("expected identical build to join the flight")
+		}
+		go func() {
+			p := new(gaia.CreatePipeline)
+			f.wait(p)
+			results <- p
+		}()
+	}
+	other, ok := joinBuildFlight("main\x00other")
+	if !ok {
+		t.Fatal("expected build of other sources to start its own flight")
+	}
+	defer other.finish(new(gaia.CreatePipeline))
+
+	select {
+	case <-results:
+		t.Fatal("expected identical builds to wait for the flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	leader.finish(&gaia.CreatePipeline{Status: pipelineCompleteStatus, StatusType: gaia.CreatePipelineSuccess, Output: "built"})
+	for i := 0; i < 2; i++ {
+		p := <-results
+		if p.StatusType != gaia.CreatePipelineSuccess || p.Output != "built" || p.Status != pipelineCompleteStatus {
+			t.Fatalf("expected result of the shared build but got %+v", p)
+		}
+	}
+
+	// A finished flight is not joined anymore
+	next, ok := joinBuildFlight("main\x00hash")
+	if !ok {
+		t.Fatal("expected new flight after the previous one finished")
+	}
+	next.finish(new(gaia.CreatePipeline))
+}
+
+func TestCreatePipelineJoinedBuildNotRecorded(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCreatePipelineJoinedBuildNotRecorded")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.SourceRoot = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	services.MockStorageService(new(mockCreatePipelineStore))
+	defer services.MockStorageService(nil)
+	active := GetGlobalActivePipelines()
+	defer SetGlobalActivePipelines(active)
+	SetGlobalActivePipelines(NewActivePipelines())
+
+	mockType := gaia.PipelineType("fingerprint")
+	RegisterBuildPipeline(mockType, func() BuildPipeline { return &fingerprintBuildPipeline{} })
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+	notifier := &recordingNotifier{}
+	RegisterNotifier(notifier)
+	defer func() {
+		notifiersLock.Lock()
+		notifiers = nil
+		notifiersLock.Unlock()
+	}()
+
+	src := filepath.Join(tmp, "src")
+	_ = os.Mkdir(src, 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, "main.txt"), []byte("content"), 0600)
+	p := &gaia.CreatePipeline{SourcePath: src}
+	p.Pipeline.Name = "TestCreatePipelineJoinedBuildNotRecorded"
+	p.Pipeline.Type = mockType
+	p.Pipeline.NotificationPolicy = gaia.NotifyAlways
+
+	// An identical build is in progress
+	sourceHash, err := hashSourceTree(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identical := *p
+	identical.Pipeline.Repo = &gaia.GitRepo{}
+	key, err := buildFlightKey(&identical, sourceHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader, ok := joinBuildFlight(key)
+	if !ok {
+		t.Fatal("expected new flight")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		CreatePipeline(p)
+		close(done)
+	}()
+
+	// Give the build time to join the flight
+	time.Sleep(100 * time.Millisecond)
+	leader.finish(&gaia.CreatePipeline{Status: pipelineCompleteStatus, StatusType: gaia.CreatePipelineSuccess, Output: "built"})
+	<-done
+
+	// The build which has been joined records and notifies the result
+	if p.Output != "built" {
+		t.Fatalf("expected result of the joined build but got '%s'", p.Output)
+	}
+	if len(notifier.notifications) != 0 {
+		t.Fatalf("expected no notification of the joined build but got %v", notifier.notifications)
+	}
+	if history := GetHistory(p.Pipeline.Name, 0); len(history) != 0 {
+		t.Fatalf("expected no history entry of the joined build but got %v", history)
+	}
+}
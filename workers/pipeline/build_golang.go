@@ -1,9 +1,12 @@
 package pipeline
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -14,6 +17,9 @@ import (
 
 const (
 	golangBinaryName = "go"
+
+	// golangPluginSuffix is the suffix of go plugin modules.
+	golangPluginSuffix = ".so"
 )
 
 // BuildPipelineGolang is the real implementation of BuildPipeline for golang
@@ -23,6 +29,10 @@ type BuildPipelineGolang struct {
 
 // PrepareEnvironment prepares the environment before we start the build process.
 func (b *BuildPipelineGolang) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// create uniqueName for destination folder
 	uniqueName := uuid.Must(uuid.NewV4(), nil)
 
@@ -31,7 +41,7 @@ func (b *BuildPipelineGolang) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	cloneFolder := filepath.Join(goPath, srcFolder, uniqueName.String())
 	err := os.MkdirAll(cloneFolder, 0700)
 	if err != nil {
-		return err
+		return prepareFailed(err)
 	}
 
 	// Set new generated path in pipeline obj for later usage
@@ -40,16 +50,80 @@ func (b *BuildPipelineGolang) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	}
 	p.Pipeline.Repo.LocalDest = cloneFolder
 	p.Pipeline.UUID = uniqueName.String()
+
+	// Plugin modules must be built by the toolchain of this host
+	if p.GoPlugin {
+		if err := checkGolangPluginMode(p); err != nil {
+			p.Output = err.Error()
+			return prepareFailed(err)
+		}
+	}
+	if p.TinyGo {
+		if err := checkTinyGoMode(p); err != nil {
+			p.Output = err.Error()
+			return prepareFailed(err)
+		}
+	}
+	return nil
+}
+
+// checkGolangPluginMode checks if the host can load the go plugin module of
+// the given pipeline. Go plugins are only supported on linux. They can only
+// be loaded by a process built with the same go version and cgo enabled.
+func checkGolangPluginMode(p *gaia.CreatePipeline) error {
+	if hostOS != "linux" {
+		return fmt.Errorf("go plugin modules are not supported on %s", hostOS)
+	}
+	if p.Target != nil || len(p.Matrix) > 0 {
+		return errors.New("go plugin modules cannot be built for other targets")
+	}
+
+	path, err := exec.LookPath(golangBinaryName)
+	if err != nil {
+		return err
+	}
+	output, err := executeCmd(phasePrepare, path, []string{"env", "GOVERSION", "CGO_ENABLED"}, os.Environ(), "")
+	if err != nil {
+		return fmt.Errorf("cannot get go environment: %s", commandOutput(output, err))
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return fmt.Errorf("cannot parse go environment: %s", string(output))
+	}
+	if fields[0] != runtime.Version() {
+		return fmt.Errorf("go plugin modules require go %s but the toolchain is %s", runtime.Version(), fields[0])
+	}
+	if fields[1] != "1" {
+		return errors.New("go plugin modules require cgo to be enabled")
+	}
 	return nil
 }
 
+// golangOutputName returns the file name of the build result of the given
+// go pipeline. Plugin modules get the shared object suffix.
+func golangOutputName(p *gaia.CreatePipeline) string {
+	if p.GoPlugin {
+		return buildOutputName(p) + golangPluginSuffix
+	}
+	return buildOutputName(p)
+}
+
+// CheckEnvironment checks if the Go toolchain is installed.
+func (b *BuildPipelineGolang) CheckEnvironment() error {
+	return checkToolchain("Go", golangBinaryName)
+}
+
 // ExecuteBuild executes the golang build process
 func (b *BuildPipelineGolang) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Look for golang executable
 	path, err := exec.LookPath(golangBinaryName)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot find go executable", "error", err.Error())
-		return err
+		return compileFailed(err)
 	}
 	goPath := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpGoFolder)
 
@@ -60,7 +134,17 @@ func (b *BuildPipelineGolang) ExecuteBuild(p *gaia.CreatePipeline) error {
 		"./...",
 	}
 
-	env := append(os.Environ(), "GOPATH="+goPath)
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+	env = append(env, "GOPATH="+goPath)
+
+	// Cross compile if a specific target has been requested
+	if p.Target != nil {
+		env = append(env, "GOOS="+p.Target.OS, "GOARCH="+p.Target.Arch)
+	}
 
 	// Set local destination
 	localDest := ""
@@ -68,58 +152,112 @@ func (b *BuildPipelineGolang) ExecuteBuild(p *gaia.CreatePipeline) error {
 		localDest = p.Pipeline.Repo.LocalDest
 	}
 
+	// Replace modules with the local copies in the build context
+	if len(p.Replace) > 0 {
+		if _, err := os.Stat(filepath.Join(localDest, "go.mod")); err != nil {
+			p.Output = "replacing modules requires a go.mod file in the repository"
+			return compileFailed(err)
+		}
+		editArgs := []string{"mod", "edit"}
+		for _, module := range replacedModules(p) {
+			editArgs = append(editArgs, fmt.Sprintf("-replace=%s=./%s", module, filepath.ToSlash(replacementPath(module))))
+		}
+		output, err := executeBuildCmd(p, phasePrepare, path, editArgs, env, localDest)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot replace modules", "error", err.Error(), "output", string(output))
+			p.Output = string(output)
+			return compileFailed(err)
+		}
+	}
+
 	// Execute and wait until finish or timeout
-	output, err := executeCmd(path, args, env, localDest)
+	output, err := executeBuildCmd(p, phasePrepare, path, args, env, localDest)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot get dependencies", "error", err.Error(), "output", string(output))
 		p.Output = string(output)
-		return err
+		return compileFailed(err)
 	}
 
 	// Set command args for build
-	args = []string{
-		"build",
-		"-o",
-		appendTypeToName(p.Pipeline.Name, p.Pipeline.Type),
+	compiler := path
+	args = []string{"build"}
+	if p.GoPlugin {
+		args = append(args, "-buildmode=plugin")
+	}
+	args = append(args, "-o", golangOutputName(p))
+	if p.TinyGo {
+		compiler, err = exec.LookPath(tinyGoBinaryName)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot find tinygo executable", "error", err.Error())
+			return compileFailed(err)
+		}
+		args = tinyGoBuildArgs(p)
 	}
 
 	// Execute and wait until finish or timeout
-	output, err = executeCmd(path, args, env, localDest)
+	output, err = executeBuildCmd(p, phaseCompile, compiler, args, env, localDest)
 	p.Output = string(output)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot build pipeline", "error", err.Error(), "output", string(output))
-		return err
+		if p.TinyGo {
+			err = tinyGoBuildFailed(p, err)
+		}
+		return compileFailed(err)
+	}
+
+	// Run the static analysis before the binary gets copied
+	if p.Lint {
+		output, err = lintGolang(p, path, env, localDest)
+		p.Output += string(output)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("static analysis failed", "error", err.Error(), "output", string(output))
+			return compileFailed(err)
+		}
 	}
 
 	// Build has been finished. Set execution path to the build result archive.
 	// This will be used during pipeline verification phase which will happen after this step.
-	p.Pipeline.ExecPath = filepath.Join(localDest, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
+	p.Pipeline.ExecPath = filepath.Join(localDest, golangOutputName(p))
+	p.Pipeline.PluginModule = p.GoPlugin
 
 	return nil
 }
 
+// lintGolang runs go vet and the configured linter on all packages.
+func lintGolang(p *gaia.CreatePipeline, goPath string, env []string, dir string) ([]byte, error) {
+	output, err := executeBuildCmd(p, phaseCompile, goPath, []string{"vet", "./..."}, env, dir)
+	if err != nil || gaia.Cfg.GoLinter == "" {
+		return output, err
+	}
+
+	linterPath, err := exec.LookPath(gaia.Cfg.GoLinter)
+	if err != nil {
+		return append(output, []byte(fmt.Sprintf("cannot find linter %s", gaia.Cfg.GoLinter))...), err
+	}
+	out, err := executeBuildCmd(p, phaseCompile, linterPath, []string{"./..."}, env, dir)
+	return append(output, out...), err
+}
+
 // CopyBinary copies the final compiled archive to the
 // destination folder.
 func (b *BuildPipelineGolang) CopyBinary(p *gaia.CreatePipeline) error {
-	// Define src and destination
-	src := filepath.Join(p.Pipeline.Repo.LocalDest, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-
-	// Copy binary
-	if err := copyFileContents(src, dest); err != nil {
-		return err
+	if p == nil {
+		return errNilCreatePipeline
 	}
 
-	// Set +x (execution right) for pipeline
-	return os.Chmod(dest, gaia.ExecutablePermission)
+	// Define src and destination
+	src := filepath.Join(p.Pipeline.Repo.LocalDest, golangOutputName(p))
+	dest := binaryDestination(p)
+
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
 }
 
 // SavePipeline saves the current pipeline configuration.
 func (b *BuildPipelineGolang) SavePipeline(p *gaia.Pipeline) error {
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
-	p.ExecPath = dest
 	p.Type = gaia.PTypeGolang
-	p.Name = strings.TrimSuffix(filepath.Base(dest), typeDelimiter+gaia.PTypeGolang.String())
+	p.ExecPath = GetExecPath(*p)
+	p.Name = filepath.Base(p.Name)
 	p.Created = time.Now()
 	// Our pipeline is finished constructing. Save it.
 	storeService, _ := services.StorageService()
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -189,6 +190,34 @@ func TestCopyBinarySrcDoesNotExistGo(t *testing.T) {
 	}
 }
 
+func TestCopyBinaryPipelineFolderMissingGo(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCopyBinaryPipelineFolderMissingGo")
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = filepath.Join(tmp, "pipelines")
+	// Initialize shared logger
+	buf := new(bytes.Buffer)
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.Trace,
+		Output: buf,
+		Name:   "Gaia",
+	})
+	b := new(BuildPipelineGolang)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = "go"
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	src := filepath.Join(tmp, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
+	_ = ioutil.WriteFile(src, []byte("testcontent"), 0666)
+	err := b.CopyBinary(p)
+	if err == nil {
+		t.Fatal("error was expected when copying binary but none occurred")
+	}
+	if !strings.Contains(err.Error(), gaia.Cfg.PipelinePath) {
+		t.Fatal("error should name the missing pipelines folder. was: ", err)
+	}
+}
+
 func TestSavePipelineGo(t *testing.T) {
 	defer os.Remove("gaia.db")
 	gaia.Cfg = new(gaia.Config)
@@ -234,3 +263,253 @@ func TestSavePipelineSaveErrors(t *testing.T) {
 		t.Fatal("error message was not the expected message. was: ", err.Error())
 	}
 }
+
+func TestBuildOutputNameWindows(t *testing.T) {
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeGolang
+
+	p.Target = &gaia.BuildTarget{OS: "windows", Arch: "amd64"}
+	if name := buildOutputName(p); name != "main_golang.exe" {
+		t.Fatalf("expected main_golang.exe for windows target but got %s", name)
+	}
+	p.Target = &gaia.BuildTarget{OS: "linux", Arch: "amd64"}
+	if name := buildOutputName(p); name != "main_golang" {
+		t.Fatalf("expected main_golang for linux target but got %s", name)
+	}
+
+	// Without a target the host decides
+	hostOS = "windows"
+	defer func() {
+		hostOS = runtime.GOOS
+	}()
+	p.Target = nil
+	if name := buildOutputName(p); name != "main_golang.exe" {
+		t.Fatalf("expected main_golang.exe on windows host but got %s", name)
+	}
+	if name := appendTypeToName("main", gaia.PTypePython); name != "main_python" {
+		t.Fatalf("expected no executable extension for python but got %s", name)
+	}
+
+	// The type must still be detected and the name restored
+	pType, err := getPipelineType("main_golang.exe")
+	if err != nil || pType != gaia.PTypeGolang {
+		t.Fatalf("expected type golang but got %s (%v)", pType, err)
+	}
+}
+
+func TestExecuteBuildLintGo(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildLintGo")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	b := new(BuildPipelineGolang)
+	p := new(gaia.CreatePipeline)
+
+	// Static analysis is disabled by default
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	if actualArgs := os.Getenv("CMD_ARGS"); strings.Contains(actualArgs, "vet") {
+		t.Fatalf("go vet should not run by default. args: %s", actualArgs)
+	}
+
+	// Run go vet and the configured linter
+	linter := filepath.Join(tmp, "staticcheck")
+	_ = ioutil.WriteFile(linter, []byte("#!/bin/sh\n"), 0700)
+	gaia.Cfg.GoLinter = linter
+	p.Lint = true
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	actualArgs := os.Getenv("CMD_ARGS")
+	for _, expected := range []string{"vet,./...", linter + ",./..."} {
+		if !strings.Contains(actualArgs, expected) {
+			t.Fatalf("expected args '%s' actual args '%s'", expected, actualArgs)
+		}
+	}
+
+	// A missing linter fails the build
+	gaia.Cfg.GoLinter = "gaia-nonexistent-linter"
+	if err := b.ExecuteBuild(p); err == nil || !strings.Contains(p.Output, "cannot find linter") {
+		t.Fatalf("expected missing linter error but got: %v", err)
+	}
+}
+
+func TestExecuteBuildReplaceGo(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildReplaceGo")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	b := new(BuildPipelineGolang)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	p.Replace = map[string]string{"example.com/shared": "/src/shared"}
+
+	// Replacing requires modules
+	if err := b.ExecuteBuild(p); err == nil {
+		t.Fatal("expected error without go.mod file")
+	}
+
+	_ = ioutil.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module example.com/pipeline"), 0600)
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	expectedArgs := "mod,edit,-replace=example.com/shared=./.gaia/replace/example_com_shared"
+	if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, expectedArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedArgs, actualArgs)
+	}
+}
+
+func TestCheckGolangPluginMode(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+		hostOS = runtime.GOOS
+		_ = os.Unsetenv("GO_WANT_HELPER_PROCESS")
+		_ = os.Unsetenv("STDOUT")
+	}()
+	gaia.Cfg = new(gaia.Config)
+	_ = os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	hostOS = "linux"
+	p := new(gaia.CreatePipeline)
+	p.GoPlugin = true
+
+	_ = os.Setenv("STDOUT", runtime.Version()+"\n1\n")
+	if err := checkGolangPluginMode(p); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Setenv("STDOUT", "go0.1\n1\n")
+	if err := checkGolangPluginMode(p); err == nil || !strings.Contains(err.Error(), "go0.1") {
+		t.Fatalf("expected toolchain mismatch error but got: %v", err)
+	}
+
+	_ = os.Setenv("STDOUT", runtime.Version()+"\n0\n")
+	if err := checkGolangPluginMode(p); err == nil || !strings.Contains(err.Error(), "cgo") {
+		t.Fatalf("expected cgo error but got: %v", err)
+	}
+
+	p.Target = &gaia.BuildTarget{OS: "linux", Arch: "arm64"}
+	if err := checkGolangPluginMode(p); err == nil {
+		t.Fatal("expected error for plugin modules with build target")
+	}
+
+	hostOS = "darwin"
+	if err := checkGolangPluginMode(new(gaia.CreatePipeline)); err == nil || !strings.Contains(err.Error(), "darwin") {
+		t.Fatalf("expected unsupported host error but got: %v", err)
+	}
+}
+
+func TestExecuteBuildPluginGo(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildPluginGo")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	b := new(BuildPipelineGolang)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: filepath.Join(tmp, "src")}
+	p.GoPlugin = true
+	_ = os.MkdirAll(p.Pipeline.Repo.LocalDest, 0700)
+
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	expectedArgs := "build,-buildmode=plugin,-o,main_golang.so"
+	if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, expectedArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedArgs, actualArgs)
+	}
+	if !p.Pipeline.PluginModule || filepath.Base(p.Pipeline.ExecPath) != "main_golang.so" {
+		t.Fatalf("expected plugin module but got exec path %s (plugin module: %v)", p.Pipeline.ExecPath, p.Pipeline.PluginModule)
+	}
+
+	// The plugin module is installed under the pipeline binary name with the plugin suffix
+	_ = ioutil.WriteFile(p.Pipeline.ExecPath, []byte("plugin"), 0600)
+	if err := b.CopyBinary(p); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(tmp, "main_golang.so")); err != nil || string(content) != "plugin" {
+		t.Fatalf("expected installed plugin module. error: %v", err)
+	}
+	if execPath := GetExecPath(p.Pipeline); execPath != filepath.Join(tmp, "main_golang.so") {
+		t.Fatalf("expected exec path of the installed plugin module but got %s", execPath)
+	}
+	if err := writeBinaryMetadata(binaryDestination(p), p.Pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if !isPluginModule(tmp, "main_golang.so") {
+		t.Fatal("expected metadata to flag the plugin module")
+	}
+}
+
+func TestExecuteBuildWasmGo(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildWasmGo")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	b := new(BuildPipelineGolang)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: filepath.Join(tmp, "src")}
+	p.Target = &gaia.BuildTarget{OS: "wasip1", Arch: "wasm"}
+	_ = os.MkdirAll(p.Pipeline.Repo.LocalDest, 0700)
+
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	expectedArgs := "build,-o,main_golang.wasm"
+	if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, expectedArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedArgs, actualArgs)
+	}
+
+	// The WebAssembly binary is installed into the pipelines folder
+	_ = ioutil.WriteFile(p.Pipeline.ExecPath, []byte("wasm"), 0600)
+	if err := b.CopyBinary(p); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(tmp, "main_golang.wasm")); err != nil || string(content) != "wasm" {
+		t.Fatalf("expected installed WebAssembly binary. error: %v", err)
+	}
+
+	services.MockStorageService(&mockStorer{})
+	defer services.MockStorageService(nil)
+	if err := b.SavePipeline(&p.Pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if p.Pipeline.ExecPath != filepath.Join(tmp, "main_golang.wasm") {
+		t.Fatalf("expected exec path of the WebAssembly binary but got %s", p.Pipeline.ExecPath)
+	}
+	if pType, err := getPipelineType(filepath.Base(p.Pipeline.ExecPath)); err != nil || pType != gaia.PTypeGolang {
+		t.Fatalf("expected go pipeline but got %s: %v", pType, err)
+	}
+}
@@ -0,0 +1,273 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	ghcBinaryName   = "ghc"
+	stackBinaryName = "stack"
+	cabalBinaryName = "cabal"
+
+	// stackProjectFile marks haskell pipelines which are built with stack.
+	stackProjectFile = "stack.yaml"
+
+	// cabalProjectFile marks haskell projects with several cabal packages.
+	cabalProjectFile = "cabal.project"
+
+	// haskellInstallFolder is the folder inside of the sources the
+	// executable of the pipeline is installed to.
+	haskellInstallFolder = "gaia-bin"
+
+	// haskellCacheFolder is the folder inside of the haskell temp folder
+	// where the work directories of the builds are kept between builds.
+	haskellCacheFolder = "cache"
+)
+
+// haskellBuildTool defines how a haskell pipeline is built.
+type haskellBuildTool struct {
+	binary string
+
+	// workDir is the folder inside of the sources which contains
+	// the build results. It is kept between builds of a pipeline.
+	workDir string
+
+	// rootEnv is the variable which points the build tool to its
+	// package store. The store is shared by all builds.
+	rootEnv string
+
+	dependencyArgs []string
+	installArgs    []string
+}
+
+var (
+	stackBuildTool = haskellBuildTool{
+		binary:         stackBinaryName,
+		workDir:        ".stack-work",
+		rootEnv:        "STACK_ROOT",
+		dependencyArgs: []string{"build", "--only-dependencies"},
+		installArgs:    []string{"install", "--local-bin-path", haskellInstallFolder},
+	}
+	cabalBuildTool = haskellBuildTool{
+		binary:         cabalBinaryName,
+		workDir:        "dist-newstyle",
+		rootEnv:        "CABAL_DIR",
+		dependencyArgs: []string{"build", "--only-dependencies"},
+		installArgs:    []string{"install", "--installdir=" + haskellInstallFolder, "--install-method=copy", "--overwrite-policy=always"},
+	}
+)
+
+// BuildPipelineHaskell is the real implementation of BuildPipeline for haskell
+type BuildPipelineHaskell struct {
+	Type gaia.PipelineType
+}
+
+// PrepareEnvironment prepares the environment before we start the build process.
+func (b *BuildPipelineHaskell) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// GHC and one of the build tools are required for the build
+	if err := b.CheckEnvironment(); err != nil {
+		return prepareFailed(err)
+	}
+
+	// create uniqueName for destination folder
+	uniqueName := uuid.Must(uuid.NewV4(), nil)
+
+	// Create local temp folder for clone
+	cloneFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpHaskellFolder, srcFolder, uniqueName.String())
+	err := os.MkdirAll(cloneFolder, 0700)
+	if err != nil {
+		return prepareFailed(err)
+	}
+
+	// Set new generated path in pipeline obj for later usage
+	if p.Pipeline.Repo == nil {
+		p.Pipeline.Repo = &gaia.GitRepo{}
+	}
+	p.Pipeline.Repo.LocalDest = cloneFolder
+	p.Pipeline.UUID = uniqueName.String()
+	return nil
+}
+
+// CheckEnvironment checks if GHC and stack or cabal are installed.
+func (b *BuildPipelineHaskell) CheckEnvironment() error {
+	if err := checkToolchain("Haskell", ghcBinaryName); err != nil {
+		return err
+	}
+	if checkToolchain("Haskell", stackBinaryName) == nil {
+		return nil
+	}
+	if checkToolchain("Haskell", cabalBinaryName) == nil {
+		return nil
+	}
+	return fmt.Errorf("Haskell toolchain not installed on this server: cannot find %s or %s", stackBinaryName, cabalBinaryName)
+}
+
+// ExecuteBuild executes the haskell build process
+func (b *BuildPipelineHaskell) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Set local destination
+	localDest := ""
+	if p.Pipeline.Repo != nil {
+		localDest = p.Pipeline.Repo.LocalDest
+	}
+
+	// Stack projects are built with stack, all others with cabal
+	tool, err := findHaskellBuildTool(localDest)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+	path, err := exec.LookPath(tool.binary)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot find haskell build tool", "tool", tool.binary, "error", err.Error())
+		return compileFailed(err)
+	}
+
+	// Set build environment. The package store is shared by all builds.
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+	haskellFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpHaskellFolder)
+	env = append(env, tool.rootEnv+"="+filepath.Join(haskellFolder, tool.binary))
+
+	// Reuse the work directory of the last build of the pipeline
+	workDir := filepath.Join(localDest, tool.workDir)
+	cache := filepath.Join(haskellFolder, haskellCacheFolder, url.PathEscape(p.Pipeline.Name), tool.workDir)
+	restoreHaskellCache(cache, workDir)
+	defer saveHaskellCache(workDir, cache)
+
+	// Build the dependencies first. They take most of the time.
+	output, err := executeBuildCmd(p, phasePrepare, path, tool.dependencyArgs, env, localDest)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot build dependencies", "error", err.Error(), "output", string(output))
+		p.Output = string(output)
+		return compileFailed(err)
+	}
+
+	// Execute and wait until finish or timeout
+	output, err = executeBuildCmd(p, phaseCompile, path, tool.installArgs, env, localDest)
+	p.Output = string(output)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot build pipeline", "error", err.Error(), "output", string(output))
+		return compileFailed(err)
+	}
+
+	// Build has been finished. Set execution path to the build result archive.
+	// This will be used during pipeline verification phase which will happen after this step.
+	execPath, err := findHaskellExecutable(localDest)
+	if err != nil {
+		p.Output += err.Error()
+		return compileFailed(err)
+	}
+	p.Pipeline.ExecPath = execPath
+
+	return nil
+}
+
+// findHaskellBuildTool returns the build tool of the haskell project in the given folder.
+func findHaskellBuildTool(dir string) (haskellBuildTool, error) {
+	if _, err := os.Stat(filepath.Join(dir, stackProjectFile)); err == nil {
+		return stackBuildTool, nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, cabalProjectFile)); err == nil {
+		return cabalBuildTool, nil
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.cabal")); len(files) > 0 {
+		return cabalBuildTool, nil
+	}
+	return haskellBuildTool{}, fmt.Errorf("cannot find %s, %s or a cabal file in repository", stackProjectFile, cabalProjectFile)
+}
+
+// findHaskellExecutable returns the path of the executable which has
+// been installed by the build. The pipeline must define exactly one.
+func findHaskellExecutable(dir string) (string, error) {
+	files, err := ioutil.ReadDir(filepath.Join(dir, haskellInstallFolder))
+	if err != nil {
+		return "", err
+	}
+	var executables []string
+	for _, file := range files {
+		if !file.IsDir() {
+			executables = append(executables, file.Name())
+		}
+	}
+	if len(executables) != 1 {
+		return "", fmt.Errorf("pipeline must define exactly one executable but found: %s", strings.Join(executables, ", "))
+	}
+	return filepath.Join(dir, haskellInstallFolder, executables[0]), nil
+}
+
+// restoreHaskellCache moves the cached work directory into the sources.
+func restoreHaskellCache(cache, workDir string) {
+	if _, err := os.Stat(cache); err != nil {
+		return
+	}
+	if err := os.Rename(cache, workDir); err != nil {
+		gaia.Cfg.Logger.Debug("cannot restore haskell build cache", "error", err.Error())
+	}
+}
+
+// saveHaskellCache moves the work directory of the build into the cache.
+func saveHaskellCache(workDir, cache string) {
+	if _, err := os.Stat(workDir); err != nil {
+		return
+	}
+	_ = os.RemoveAll(cache)
+	if err := os.MkdirAll(filepath.Dir(cache), 0700); err != nil {
+		gaia.Cfg.Logger.Debug("cannot create haskell build cache", "error", err.Error())
+		return
+	}
+	if err := os.Rename(workDir, cache); err != nil {
+		gaia.Cfg.Logger.Debug("cannot save haskell build cache", "error", err.Error())
+	}
+}
+
+// CopyBinary copies the final compiled binary to the
+// destination folder.
+func (b *BuildPipelineHaskell) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Define src and destination
+	src, err := findHaskellExecutable(p.Pipeline.Repo.LocalDest)
+	if err != nil {
+		return copyFailed(err)
+	}
+	dest := binaryDestination(p)
+
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
+}
+
+// SavePipeline saves the current pipeline configuration.
+func (b *BuildPipelineHaskell) SavePipeline(p *gaia.Pipeline) error {
+	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
+	p.ExecPath = dest
+	p.Type = gaia.PTypeHaskell
+	p.Name = filepath.Base(p.Name)
+	p.Created = time.Now()
+	// Our pipeline is finished constructing. Save it.
+	storeService, _ := services.StorageService()
+	return storeService.PipelinePut(p)
+}
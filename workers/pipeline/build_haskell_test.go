@@ -0,0 +1,163 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestPrepareEnvironmentHaskell(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPrepareEnvironmentHaskell")
+	defer os.RemoveAll(tmp)
+	bin := filepath.Join(tmp, "bin")
+	_ = os.MkdirAll(bin, 0700)
+	defer fakeToolchain(t, bin, ghcBinaryName, cabalBinaryName)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	b := new(BuildPipelineHaskell)
+	p := new(gaia.CreatePipeline)
+	err := b.PrepareEnvironment(p)
+	if err != nil {
+		t.Fatal("error was not expected when preparing environment: ", err)
+	}
+	var expectedDest = regexp.MustCompile(`^/.*/tmp/haskell/src/[^/]+$`)
+	if !expectedDest.MatchString(p.Pipeline.Repo.LocalDest) {
+		t.Fatalf("expected destination is '%s', but was '%s'", expectedDest, p.Pipeline.Repo.LocalDest)
+	}
+}
+
+func TestPrepareEnvironmentMissingHaskellToolchain(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPrepareEnvironmentMissingHaskellToolchain")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	b := new(BuildPipelineHaskell)
+
+	restore := fakeToolchain(t, tmp, stackBinaryName)
+	err := b.PrepareEnvironment(new(gaia.CreatePipeline))
+	restore()
+	if err == nil || !strings.Contains(err.Error(), "cannot find ghc") {
+		t.Fatalf("expected missing ghc error but got: %v", err)
+	}
+
+	_ = os.Remove(filepath.Join(tmp, stackBinaryName))
+	defer fakeToolchain(t, tmp, ghcBinaryName)()
+	err = b.PrepareEnvironment(new(gaia.CreatePipeline))
+	if err == nil || !strings.Contains(err.Error(), "cannot find stack or cabal") {
+		t.Fatalf("expected missing build tool error but got: %v", err)
+	}
+}
+
+func TestExecuteBuildHaskell(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildHaskell")
+	defer os.RemoveAll(tmp)
+	bin := filepath.Join(tmp, "bin")
+	_ = os.MkdirAll(bin, 0700)
+	defer fakeToolchain(t, bin, ghcBinaryName, stackBinaryName, cabalBinaryName)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	src := filepath.Join(tmp, "src")
+	_ = os.MkdirAll(filepath.Join(src, haskellInstallFolder), 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, stackProjectFile), []byte("resolver: lts-22.0"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(src, haskellInstallFolder, "pipeline"), []byte("binary"), 0700)
+	_ = os.MkdirAll(filepath.Join(src, stackBuildTool.workDir), 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, stackBuildTool.workDir, "cached"), []byte("cached"), 0600)
+
+	b := new(BuildPipelineHaskell)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeHaskell
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: src}
+
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	actualArgs := os.Getenv("CMD_ARGS")
+	for _, expected := range []string{"stack,build,--only-dependencies", "stack,install,--local-bin-path," + haskellInstallFolder} {
+		if !strings.Contains(actualArgs, expected) {
+			t.Fatalf("expected args '%s' actual args '%s'", expected, actualArgs)
+		}
+	}
+	if p.Pipeline.ExecPath != filepath.Join(src, haskellInstallFolder, "pipeline") {
+		t.Fatalf("unexpected exec path %s", p.Pipeline.ExecPath)
+	}
+
+	// The work directory is kept for the next build of the pipeline
+	cache := filepath.Join(tmp, gaia.TmpFolder, gaia.TmpHaskellFolder, haskellCacheFolder, "main", stackBuildTool.workDir)
+	if content, err := ioutil.ReadFile(filepath.Join(cache, "cached")); err != nil || string(content) != "cached" {
+		t.Fatalf("expected cached work directory. error: %v", err)
+	}
+	restoreHaskellCache(cache, filepath.Join(src, stackBuildTool.workDir))
+	if _, err := os.Stat(filepath.Join(src, stackBuildTool.workDir, "cached")); err != nil {
+		t.Fatalf("expected restored work directory. error: %v", err)
+	}
+
+	if err := b.CopyBinary(p); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(tmp, "main_haskell")); err != nil || string(content) != "binary" {
+		t.Fatalf("file content did not equal src content. error: %v", err)
+	}
+}
+
+func TestFindHaskellBuildTool(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestFindHaskellBuildTool")
+	defer os.RemoveAll(tmp)
+
+	if _, err := findHaskellBuildTool(tmp); err == nil {
+		t.Fatal("expected error without project file")
+	}
+	_ = ioutil.WriteFile(filepath.Join(tmp, "pipeline.cabal"), []byte("name: pipeline"), 0600)
+	if tool, err := findHaskellBuildTool(tmp); err != nil || tool.binary != cabalBinaryName {
+		t.Fatalf("expected cabal but got %s: %v", tool.binary, err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(tmp, stackProjectFile), []byte("resolver: lts-22.0"), 0600)
+	if tool, err := findHaskellBuildTool(tmp); err != nil || tool.binary != stackBinaryName {
+		t.Fatalf("expected stack but got %s: %v", tool.binary, err)
+	}
+}
+
+func TestFindHaskellExecutable(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestFindHaskellExecutable")
+	defer os.RemoveAll(tmp)
+	_ = os.MkdirAll(filepath.Join(tmp, haskellInstallFolder), 0700)
+	_ = ioutil.WriteFile(filepath.Join(tmp, haskellInstallFolder, "a"), nil, 0700)
+	_ = ioutil.WriteFile(filepath.Join(tmp, haskellInstallFolder, "b"), nil, 0700)
+	if _, err := findHaskellExecutable(tmp); err == nil || !strings.Contains(err.Error(), "exactly one executable but found: a, b") {
+		t.Fatalf("expected error for several executables but got: %v", err)
+	}
+}
+
+func TestSavePipelineHaskell(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = "/tmp/pipelines/"
+	p := new(gaia.Pipeline)
+	p.Name = "main"
+	p.Type = gaia.PTypeHaskell
+	b := new(BuildPipelineHaskell)
+	m := new(nodeJSMockStorer)
+	services.MockStorageService(m)
+	defer services.MockStorageService(nil)
+	if err := b.SavePipeline(p); err != nil {
+		t.Fatal("something went wrong. wasn't supposed to get error: ", err)
+	}
+	if p.Name != "main" || p.Type != gaia.PTypeHaskell || p.ExecPath != "/tmp/pipelines/main_haskell" {
+		t.Fatalf("unexpected saved pipeline: %+v", p)
+	}
+}
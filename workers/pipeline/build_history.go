@@ -0,0 +1,133 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// defaultBuildHistorySize is the number of build records kept per
+// pipeline when no size has been configured.
+const defaultBuildHistorySize = 20
+
+// BuildRecord represents a single finished build of a pipeline.
+type BuildRecord struct {
+	Started  time.Time               `json:"started"`
+	Duration time.Duration           `json:"duration"`
+	Status   gaia.CreatePipelineType `json:"status"`
+	Revision string                  `json:"revision,omitempty"`
+}
+
+// buildRing is a bounded ring buffer of build records.
+type buildRing struct {
+	records []BuildRecord
+
+	// next is the index the next record is written to.
+	next int
+}
+
+// add adds the record to the ring and overwrites the oldest
+// record if the ring is full.
+func (r *buildRing) add(record BuildRecord, size int) {
+	// The configured size could have been changed. Bring the records
+	// back into order and drop the oldest ones if the ring shrinks.
+	if len(r.records) > size || (len(r.records) < size && r.next != len(r.records)) {
+		latest := r.latest(size)
+		r.records = make([]BuildRecord, 0, size)
+		for i := len(latest) - 1; i >= 0; i-- {
+			r.records = append(r.records, latest[i])
+		}
+		r.next = len(r.records) % size
+	}
+
+	if len(r.records) < size {
+		r.records = append(r.records, record)
+		r.next = len(r.records) % size
+		return
+	}
+	r.records[r.next] = record
+	r.next = (r.next + 1) % size
+}
+
+// latest returns the latest limit records with the newest first.
+func (r *buildRing) latest(limit int) []BuildRecord {
+	if limit <= 0 || limit > len(r.records) {
+		limit = len(r.records)
+	}
+
+	records := make([]BuildRecord, 0, limit)
+	for i := 1; i <= limit; i++ {
+		index := (r.next - i + len(r.records)) % len(r.records)
+		records = append(records, r.records[index])
+	}
+	return records
+}
+
+// buildHistoryStore holds the build records of all pipelines.
+type buildHistoryStore struct {
+	sync.RWMutex
+
+	rings map[string]*buildRing
+}
+
+// BuildHistory holds globally the build history of all pipelines.
+var BuildHistory = &buildHistoryStore{
+	rings: make(map[string]*buildRing),
+}
+
+// Add adds the record to the build history of the pipeline with the given name.
+func (h *buildHistoryStore) Add(name string, record BuildRecord) {
+	size := gaia.Cfg.BuildHistorySize
+	if size < 1 {
+		size = defaultBuildHistorySize
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	ring, ok := h.rings[name]
+	if !ok {
+		ring = &buildRing{}
+		h.rings[name] = ring
+	}
+	ring.add(record, size)
+}
+
+// rename moves the build history of a pipeline to the new name.
+func (h *buildHistoryStore) rename(oldName, newName string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if ring, ok := h.rings[oldName]; ok {
+		h.rings[newName] = ring
+		delete(h.rings, oldName)
+	}
+}
+
+// GetHistory returns the latest limit build records of the pipeline with
+// the given name with the newest first. All records are returned if limit
+// is zero or negative.
+func GetHistory(name string, limit int) []BuildRecord {
+	BuildHistory.RLock()
+	defer BuildHistory.RUnlock()
+
+	ring, ok := BuildHistory.rings[name]
+	if !ok {
+		return []BuildRecord{}
+	}
+	return ring.latest(limit)
+}
+
+// recordBuild adds the finished build of the given pipeline to the build history.
+func recordBuild(p *gaia.CreatePipeline, started time.Time) {
+	record := BuildRecord{
+		Started:  started,
+		Duration: time.Since(started),
+		Status:   p.StatusType,
+	}
+	if p.Pipeline.Repo != nil {
+		record.Revision = p.Pipeline.Repo.Revision
+	}
+	BuildHistory.Add(p.Pipeline.Name, record)
+}
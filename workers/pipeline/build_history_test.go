@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestGetHistory(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildHistorySize = 3
+	defer delete(BuildHistory.rings, "TestGetHistory")
+
+	if records := GetHistory("TestGetHistory", 0); len(records) != 0 {
+		t.Fatalf("expected empty history but got %d records", len(records))
+	}
+
+	for i := 0; i < 5; i++ {
+		p := new(gaia.CreatePipeline)
+		p.Pipeline.Name = "TestGetHistory"
+		p.Pipeline.Repo = &gaia.GitRepo{Revision: string(rune('a' + i))}
+		p.StatusType = gaia.CreatePipelineSuccess
+		recordBuild(p, time.Now())
+	}
+
+	// Only the latest three builds are kept, newest first
+	records := GetHistory("TestGetHistory", 0)
+	expected := []string{"e", "d", "c"}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records but got %d", len(expected), len(records))
+	}
+	for i, revision := range expected {
+		if records[i].Revision != revision {
+			t.Fatalf("expected revision %s at %d but got %s", revision, i, records[i].Revision)
+		}
+	}
+	if records := GetHistory("TestGetHistory", 1); len(records) != 1 || records[0].Revision != "e" {
+		t.Fatalf("expected only the latest record but got %v", records)
+	}
+}
+
+func TestBuildRingResize(t *testing.T) {
+	r := &buildRing{}
+	for _, revision := range []string{"a", "b", "c", "d"} {
+		r.add(BuildRecord{Revision: revision}, 3)
+	}
+
+	// Shrink the ring
+	r.add(BuildRecord{Revision: "e"}, 2)
+	if records := r.latest(0); len(records) != 2 || records[0].Revision != "e" || records[1].Revision != "d" {
+		t.Fatalf("unexpected records after shrink: %v", records)
+	}
+
+	// Grow the ring
+	r.add(BuildRecord{Revision: "f"}, 4)
+	r.add(BuildRecord{Revision: "g"}, 4)
+	expected := []string{"g", "f", "e", "d"}
+	records := r.latest(0)
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records but got %d", len(expected), len(records))
+	}
+	for i, revision := range expected {
+		if records[i].Revision != revision {
+			t.Fatalf("expected revision %s at %d but got %s", revision, i, records[i].Revision)
+		}
+	}
+}
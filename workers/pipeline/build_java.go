@@ -4,7 +4,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
@@ -29,6 +28,10 @@ type BuildPipelineJava struct {
 
 // PrepareEnvironment prepares the environment before we start the build process.
 func (b *BuildPipelineJava) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// create uniqueName for destination folder
 	uniqueName := uuid.Must(uuid.NewV4(), nil)
 
@@ -37,7 +40,7 @@ func (b *BuildPipelineJava) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	cloneFolder := filepath.Join(rootPath, srcFolder, uniqueName.String())
 	err := os.MkdirAll(cloneFolder, 0700)
 	if err != nil {
-		return err
+		return prepareFailed(err)
 	}
 
 	// Set new generated path in pipeline obj for later usage
@@ -49,15 +52,28 @@ func (b *BuildPipelineJava) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	return nil
 }
 
+// CheckEnvironment checks if the Java toolchain is installed.
+func (b *BuildPipelineJava) CheckEnvironment() error {
+	return checkToolchain("Java", mavenBinaryName)
+}
+
 // ExecuteBuild executes the java build process
 func (b *BuildPipelineJava) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Look for maven executeable
 	path, err := exec.LookPath(mavenBinaryName)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot find maven executeable", "error", err.Error())
-		return err
+		return compileFailed(err)
+	}
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
 	}
-	env := os.Environ()
 
 	// Set command args for build
 	args := []string{
@@ -73,11 +89,11 @@ func (b *BuildPipelineJava) ExecuteBuild(p *gaia.CreatePipeline) error {
 	}
 
 	// Execute and wait until finish or timeout
-	output, err := executeCmd(path, args, env, localDest)
+	output, err := executeBuildCmd(p, phaseCompile, path, args, env, localDest)
 	p.Output = string(output)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot build pipeline", "error", err.Error(), "output", string(output))
-		return err
+		return compileFailed(err)
 	}
 
 	// Build has been finished. Set execution path to the build result archive.
@@ -90,17 +106,16 @@ func (b *BuildPipelineJava) ExecuteBuild(p *gaia.CreatePipeline) error {
 // CopyBinary copies the final compiled archive to the
 // destination folder.
 func (b *BuildPipelineJava) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Define src and destination
 	src := filepath.Join(p.Pipeline.Repo.LocalDest, mavenTargetFolder, javaFinalJarName)
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-
-	// Copy binary
-	if err := copyFileContents(src, dest); err != nil {
-		return err
-	}
+	dest := binaryDestination(p)
 
-	// Set +x (execution right) for pipeline
-	return os.Chmod(dest, gaia.ExecutablePermission)
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
 }
 
 // SavePipeline saves the current pipeline configuration.
@@ -108,7 +123,7 @@ func (b *BuildPipelineJava) SavePipeline(p *gaia.Pipeline) error {
 	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
 	p.ExecPath = dest
 	p.Type = gaia.PTypeJava
-	p.Name = strings.TrimSuffix(filepath.Base(dest), typeDelimiter+gaia.PTypeJava.String())
+	p.Name = filepath.Base(p.Name)
 	p.Created = time.Now()
 	// Our pipeline is finished constructing. Save it.
 	storeService, _ := services.StorageService()
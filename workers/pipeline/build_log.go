@@ -0,0 +1,176 @@
+package pipeline
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	// buildLogFolder is the folder inside of the temp folder
+	// where the build logs are stored.
+	buildLogFolder = "logs"
+
+	// tailPollInterval is the time a follower of a build log waits
+	// for new output before it reads again.
+	tailPollInterval = 100 * time.Millisecond
+)
+
+var (
+	// buildLogs are the logs of all running builds by pipeline name.
+	buildLogs = map[string]*buildLog{}
+
+	// buildLogAliases maps the names of the entries of running build
+	// matrices to the names of the builds which own the logs.
+	buildLogAliases = map[string]string{}
+
+	// buildLogsLock protects the build logs and their aliases.
+	buildLogsLock sync.Mutex
+
+	// errNoRunningBuild is thrown when the log of a build is requested
+	// but no build of the pipeline is running.
+	errNoRunningBuild = errors.New("no build is running for this pipeline")
+)
+
+// buildLog is the log file of a running build.
+type buildLog struct {
+	file *os.File
+
+	// done is closed when the build has been finished.
+	done chan struct{}
+}
+
+// buildLogPath returns the path to the build log of the pipeline with
+// the given name. The name is escaped because it may contain a path.
+func buildLogPath(name string) string {
+	return filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, buildLogFolder, url.PathEscape(name)+".log")
+}
+
+// openBuildLog creates the build log for the pipeline with the given name.
+// The log of the previous build is replaced. The log is opened when the
+// first build of the pipeline is tracked, so the log can be followed
+// while the sources are fetched.
+func openBuildLog(name string) error {
+	path := buildLogPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	buildLogsLock.Lock()
+	defer buildLogsLock.Unlock()
+	buildLogs[name] = &buildLog{file: file, done: make(chan struct{})}
+	return nil
+}
+
+// closeBuildLog closes the build log of the pipeline with the given name
+// and stops all followers once they have read the complete log.
+func closeBuildLog(name string) {
+	buildLogsLock.Lock()
+	defer buildLogsLock.Unlock()
+
+	l, ok := buildLogs[name]
+	if !ok {
+		return
+	}
+	delete(buildLogs, name)
+	_ = l.file.Close()
+	close(l.done)
+}
+
+// aliasBuildLog writes the output of the pipeline with the given alias
+// to the build log of the pipeline with the given name. The returned
+// function removes the alias.
+func aliasBuildLog(alias, name string) func() {
+	buildLogsLock.Lock()
+	defer buildLogsLock.Unlock()
+	buildLogAliases[alias] = name
+
+	return func() {
+		buildLogsLock.Lock()
+		defer buildLogsLock.Unlock()
+		delete(buildLogAliases, alias)
+	}
+}
+
+// buildLogWriter returns the writer of the build log of the pipeline with
+// the given name or nil if no build is running.
+func buildLogWriter(name string) io.Writer {
+	buildLogsLock.Lock()
+	defer buildLogsLock.Unlock()
+
+	if owner, ok := buildLogAliases[name]; ok {
+		name = owner
+	}
+	if l, ok := buildLogs[name]; ok {
+		return l.file
+	}
+	return nil
+}
+
+// TailBuildLog opens the log of the running build of the pipeline with the
+// given name. The log is read from the beginning and followed until the
+// build has been finished.
+func TailBuildLog(name string) (io.ReadCloser, error) {
+	buildLogsLock.Lock()
+	l, ok := buildLogs[name]
+	buildLogsLock.Unlock()
+	if !ok {
+		return nil, errNoRunningBuild
+	}
+
+	file, err := os.Open(l.file.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &logFollower{file: file, done: l.done, closed: make(chan struct{})}, nil
+}
+
+// logFollower reads a build log and waits for new output
+// until the build has been finished.
+type logFollower struct {
+	file      *os.File
+	done      <-chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+	finished  bool
+}
+
+// Read reads the next output of the build. It blocks until new output is
+// available and returns io.EOF once the complete log of the finished
+// build has been read.
+func (f *logFollower) Read(b []byte) (int, error) {
+	for {
+		n, err := f.file.Read(b)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if f.finished {
+			return 0, io.EOF
+		}
+
+		select {
+		case <-f.done:
+			// Read the remaining output written before the build finished
+			f.finished = true
+		case <-f.closed:
+			return 0, os.ErrClosed
+		case <-time.After(tailPollInterval):
+		}
+	}
+}
+
+// Close stops following the build log.
+func (f *logFollower) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return f.file.Close()
+}
@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestTailBuildLog(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+		_ = os.Unsetenv("GO_WANT_HELPER_PROCESS")
+		_ = os.Unsetenv("STDOUT")
+	}()
+	tmp, _ := ioutil.TempDir("", "TestTailBuildLog")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+
+	name := "group/TestTailBuildLog"
+	if _, err := TailBuildLog(name); err != errNoRunningBuild {
+		t.Fatalf("expected error '%v' but got '%v'", errNoRunningBuild, err)
+	}
+
+	if err := openBuildLog(name); err != nil {
+		t.Fatal(err)
+	}
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = name
+	_ = os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	_ = os.Setenv("STDOUT", "first line\n")
+	if _, err := executeBuildCmd(p, phaseCompile, "go", []string{"build"}, os.Environ(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Attach to the running build and read from the beginning
+	r, err := TailBuildLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	result := make(chan string)
+	go func() {
+		content, _ := ioutil.ReadAll(r)
+		result <- string(content)
+	}()
+
+	_ = os.Setenv("STDOUT", "second line\n")
+	if _, err := executeBuildCmd(p, phaseCompile, "go", []string{"build"}, os.Environ(), ""); err != nil {
+		t.Fatal(err)
+	}
+	closeBuildLog(name)
+
+	if content := <-result; content != "first line\nsecond line\n" {
+		t.Fatalf("expected complete build log but got '%s'", content)
+	}
+	if _, err := TailBuildLog(name); err != errNoRunningBuild {
+		t.Fatal("finished build should not be tailed")
+	}
+}
+
+func TestTrackBuildOpensBuildLog(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestTrackBuildOpensBuildLog")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+
+	// The log can be followed before the sources have been fetched
+	name := "TestTrackBuildOpensBuildLog"
+	first := trackBuild(name)
+	r, err := TailBuildLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Queued builds of the pipeline write to the same log
+	second := trackBuild(name)
+	untrackBuild(name, first)
+	if _, err := TailBuildLog(name); err != nil {
+		t.Fatalf("expected log of the queued build but got %v", err)
+	}
+	untrackBuild(name, second)
+	if _, err := TailBuildLog(name); err != errNoRunningBuild {
+		t.Fatalf("expected error '%v' but got '%v'", errNoRunningBuild, err)
+	}
+}
+
+func TestBuildMatrixEntryWritesToBuildLog(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+		_ = os.Unsetenv("GO_WANT_HELPER_PROCESS")
+		_ = os.Unsetenv("STDOUT")
+	}()
+	tmp, _ := ioutil.TempDir("", "TestBuildMatrixEntryWritesToBuildLog")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+
+	name := "TestBuildMatrixEntryWritesToBuildLog"
+	b := trackBuild(name)
+	defer untrackBuild(name, b)
+
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = name
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	_ = os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	_ = os.Setenv("STDOUT", "entry output\n")
+	target := gaia.BuildTarget{OS: "linux", Arch: "arm64"}
+	buildMatrixEntry(new(BuildPipelineGolang), p, target)
+	if buildLogWriter(matrixPipelineName(name, target)) != nil {
+		t.Fatal("expected alias to be removed")
+	}
+
+	content, err := ioutil.ReadFile(buildLogPath(name))
+	if err != nil || !strings.Contains(string(content), "entry output\n") {
+		t.Fatalf("expected output of the matrix entry in the build log but got '%s': %v", string(content), err)
+	}
+}
@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+var (
+	// errMatrixNotSupported is thrown when a build matrix has been defined
+	// for a pipeline type which does not support cross compiling.
+	errMatrixNotSupported = errors.New("build matrix is only supported for golang pipelines without image")
+
+	// errInvalidBuildTarget is thrown when a build matrix entry is missing
+	// the operating system or the architecture.
+	errInvalidBuildTarget = errors.New("build target requires os and arch")
+)
+
+// validateBuildMatrix validates the build matrix of the given pipeline.
+func validateBuildMatrix(p *gaia.CreatePipeline) error {
+	if len(p.Matrix) == 0 {
+		return nil
+	}
+	if p.Pipeline.Type != gaia.PTypeGolang || p.Image != nil {
+		return errMatrixNotSupported
+	}
+	for _, target := range p.Matrix {
+		if target.OS == "" || target.Arch == "" {
+			return errInvalidBuildTarget
+		}
+	}
+	return nil
+}
+
+// matrixPipelineName returns the name of the pipeline for the given target.
+func matrixPipelineName(n string, target gaia.BuildTarget) string {
+	return fmt.Sprintf("%s-%s-%s", n, target.OS, target.Arch)
+}
+
+// executeBuildMatrix compiles and copies the given pipeline for every
+// entry of the build matrix. A failed entry does not abort the remaining
+// entries. The result of each entry is returned.
+func executeBuildMatrix(bP BuildPipeline, p *gaia.CreatePipeline) []gaia.BuildTargetResult {
+	results := make([]gaia.BuildTargetResult, 0, len(p.Matrix))
+
+	// Make sure the folder for the matrix results exists
+	err := os.MkdirAll(filepath.Join(gaia.Cfg.PipelinePath, matrixFolder), 0700)
+
+	for _, target := range p.Matrix {
+		var result gaia.BuildTargetResult
+		if err != nil {
+			result = gaia.BuildTargetResult{
+				Target:     target,
+				Name:       matrixPipelineName(p.Pipeline.Name, target),
+				StatusType: gaia.CreatePipelineFailed,
+				Output:     fmt.Sprintf("cannot create matrix folder: %s", err.Error()),
+			}
+		} else {
+			result = buildMatrixEntry(bP, p, target)
+		}
+
+		if result.StatusType == gaia.CreatePipelineFailed {
+			gaia.Cfg.Logger.Error("build matrix entry failed", "pipeline", p.Pipeline.Name, "os", target.OS, "arch", target.Arch)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// buildMatrixEntry compiles and copies the given pipeline for a single target.
+func buildMatrixEntry(bP BuildPipeline, p *gaia.CreatePipeline, target gaia.BuildTarget) gaia.BuildTargetResult {
+	entry := *p
+	entry.Pipeline.Name = matrixPipelineName(p.Pipeline.Name, target)
+	entry.Target = &target
+	entry.Matrix = nil
+	entry.MatrixEntry = true
+	entry.Output = ""
+	defer aliasBuildLog(entry.Pipeline.Name, p.Pipeline.Name)()
+
+	result := gaia.BuildTargetResult{
+		Target:     target,
+		Name:       entry.Pipeline.Name,
+		StatusType: gaia.CreatePipelineFailed,
+	}
+
+	// Run compile process
+	if err := bP.ExecuteBuild(&entry); err != nil {
+		result.Output = entry.Output
+		return result
+	}
+
+	// Copy compiled binary to the matrix folder
+	if err := bP.CopyBinary(&entry); err != nil {
+		result.Output = fmt.Sprintf("cannot copy compiled binary: %s", err.Error())
+		return result
+	}
+
+	result.StatusType = gaia.CreatePipelineSuccess
+	result.Output = entry.Output
+	return result
+}
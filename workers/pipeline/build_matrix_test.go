@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type mockMatrixBuildPipeline struct {
+	BuildPipelineGolang
+	failOS string
+	copied []string
+}
+
+func (m *mockMatrixBuildPipeline) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p.Target.OS == m.failOS {
+		p.Output = "compile error"
+		return errors.New("compile error")
+	}
+	return nil
+}
+
+func (m *mockMatrixBuildPipeline) CopyBinary(p *gaia.CreatePipeline) error {
+	m.copied = append(m.copied, binaryDestination(p))
+	return nil
+}
+
+func TestValidateBuildMatrix(t *testing.T) {
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Type = gaia.PTypeGolang
+	if err := validateBuildMatrix(p); err != nil {
+		t.Fatal("empty build matrix should be valid: ", err)
+	}
+	p.Matrix = []gaia.BuildTarget{{OS: "linux", Arch: "amd64"}}
+	if err := validateBuildMatrix(p); err != nil {
+		t.Fatal("build matrix should be valid: ", err)
+	}
+	p.Matrix = append(p.Matrix, gaia.BuildTarget{OS: "linux"})
+	if err := validateBuildMatrix(p); err != errInvalidBuildTarget {
+		t.Fatalf("expected error '%v' but got '%v'", errInvalidBuildTarget, err)
+	}
+	p.Pipeline.Type = gaia.PTypeJava
+	if err := validateBuildMatrix(p); err != errMatrixNotSupported {
+		t.Fatalf("expected error '%v' but got '%v'", errMatrixNotSupported, err)
+	}
+}
+
+func TestExecuteBuildMatrix(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildMatrix")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "test"
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Matrix = []gaia.BuildTarget{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "windows", Arch: "amd64"},
+		{OS: "darwin", Arch: "arm64"},
+	}
+	b := &mockMatrixBuildPipeline{failOS: "windows"}
+	results := executeBuildMatrix(b, p)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results but got %d", len(results))
+	}
+	expected := []gaia.CreatePipelineType{gaia.CreatePipelineSuccess, gaia.CreatePipelineFailed, gaia.CreatePipelineSuccess}
+	for i, result := range results {
+		if result.StatusType != expected[i] {
+			t.Fatalf("expected status %s for %s but got %s", expected[i], result.Name, result.StatusType)
+		}
+	}
+	if results[1].Output != "compile error" {
+		t.Fatalf("expected compile output to be reported. got: %s", results[1].Output)
+	}
+	if results[2].Name != "test-darwin-arm64" {
+		t.Fatalf("expected name test-darwin-arm64 but got %s", results[2].Name)
+	}
+
+	// Artifacts must be distinctly named and stored in the matrix folder
+	if len(b.copied) != 2 {
+		t.Fatalf("expected 2 copied artifacts but got %d", len(b.copied))
+	}
+	expectedDest := filepath.Join(tmp, matrixFolder, appendTypeToName("test-linux-amd64", gaia.PTypeGolang))
+	if b.copied[0] != expectedDest {
+		t.Fatalf("expected destination %s but got %s", expectedDest, b.copied[0])
+	}
+	if _, err := os.Stat(filepath.Join(tmp, matrixFolder)); err != nil {
+		t.Fatal("matrix folder should have been created: ", err)
+	}
+
+	// The original pipeline must be untouched
+	if p.Pipeline.Name != "test" || p.Target != nil {
+		t.Fatal("original pipeline should not have been modified")
+	}
+
+	// WebAssembly entries are not loaded as pipelines either
+	p.Matrix = []gaia.BuildTarget{{OS: "wasip1", Arch: "wasm"}}
+	b = &mockMatrixBuildPipeline{}
+	executeBuildMatrix(b, p)
+	expectedDest = filepath.Join(tmp, matrixFolder, "test-wasip1-wasm_golang"+wasmExtension)
+	if len(b.copied) != 1 || b.copied[0] != expectedDest {
+		t.Fatalf("expected destination %s but got %v", expectedDest, b.copied)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
@@ -21,6 +20,10 @@ type BuildPipelineNodeJS struct {
 
 // PrepareEnvironment prepares the environment before we start the build process.
 func (b *BuildPipelineNodeJS) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// create uniqueName for destination folder
 	uniqueName := uuid.Must(uuid.NewV4(), nil)
 
@@ -28,7 +31,7 @@ func (b *BuildPipelineNodeJS) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	cloneFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpNodeJSFolder, srcFolder, uniqueName.String(), nodeJSInternalCloneFolder)
 	err := os.MkdirAll(cloneFolder, 0700)
 	if err != nil {
-		return err
+		return prepareFailed(err)
 	}
 
 	// Set new generated path in pipeline obj for later usage
@@ -37,16 +40,26 @@ func (b *BuildPipelineNodeJS) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	}
 	p.Pipeline.Repo.LocalDest = cloneFolder
 	p.Pipeline.UUID = uniqueName.String()
+	p.Pipeline.Entrypoint = p.Entrypoint
 	return nil
 }
 
+// CheckEnvironment checks if the NodeJS toolchain is installed.
+func (b *BuildPipelineNodeJS) CheckEnvironment() error {
+	return checkToolchain("NodeJS", tarName)
+}
+
 // ExecuteBuild executes the NodeJS build process
 func (b *BuildPipelineNodeJS) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Look for Tar binary executable
 	path, err := exec.LookPath(tarName)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot find tar binary executable", "error", err.Error())
-		return err
+		return compileFailed(err)
 	}
 
 	// Set local destination
@@ -55,6 +68,19 @@ func (b *BuildPipelineNodeJS) ExecuteBuild(p *gaia.CreatePipeline) error {
 		localDest = p.Pipeline.Repo.LocalDest
 	}
 
+	// The configured entrypoint must be part of the sources
+	if err := checkEntrypoint(localDest, p.Pipeline.Entrypoint); err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Set build environment
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
 	// Set command args for archive process
 	pipelineFileName := appendTypeToName(p.Pipeline.Name, p.Pipeline.Type)
 	args := []string{
@@ -68,11 +94,11 @@ func (b *BuildPipelineNodeJS) ExecuteBuild(p *gaia.CreatePipeline) error {
 
 	// Execute and wait until finish or timeout
 	uniqueFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpNodeJSFolder, srcFolder, p.Pipeline.UUID)
-	output, err := executeCmd(path, args, os.Environ(), uniqueFolder)
+	output, err := executeBuildCmd(p, phaseCompile, path, args, env, uniqueFolder)
 	p.Output = string(output[:])
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot build pipeline", "error", err.Error(), "output", string(output[:]))
-		return err
+		return compileFailed(err)
 	}
 
 	// Build has been finished. Set execution path to the build result archive.
@@ -89,17 +115,16 @@ func (b *BuildPipelineNodeJS) ExecuteBuild(p *gaia.CreatePipeline) error {
 // CopyBinary copies the final compiled binary to the
 // destination folder.
 func (b *BuildPipelineNodeJS) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Define src and destination
 	src := filepath.Join(p.Pipeline.Repo.LocalDest, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-
-	// Copy binary
-	if err := copyFileContents(src, dest); err != nil {
-		return err
-	}
+	dest := binaryDestination(p)
 
-	// Set +x (execution right) for pipeline
-	return os.Chmod(dest, gaia.ExecutablePermission)
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
 }
 
 // SavePipeline saves the current pipeline configuration.
@@ -107,7 +132,7 @@ func (b *BuildPipelineNodeJS) SavePipeline(p *gaia.Pipeline) error {
 	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
 	p.ExecPath = dest
 	p.Type = gaia.PTypeNodeJS
-	p.Name = strings.TrimSuffix(filepath.Base(dest), typeDelimiter+gaia.PTypeNodeJS.String())
+	p.Name = filepath.Base(p.Name)
 	p.Created = time.Now()
 	// Our pipeline is finished constructing. Save it.
 	storeService, _ := services.StorageService()
@@ -0,0 +1,177 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+)
+
+const (
+	// ociDefaultDockerfile is the Dockerfile used if none has been defined.
+	ociDefaultDockerfile = "Dockerfile"
+
+	// ociDefaultTag is the image tag used if none has been defined.
+	ociDefaultTag = "latest"
+)
+
+var (
+	// ociBuilders are the supported container builders in order of preference.
+	ociBuilders = []string{"docker", "buildah"}
+
+	// ociInvalidNameChars matches all characters which are not allowed in image names.
+	ociInvalidNameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+	// errMissingRegistry is thrown when no registry has been defined for an image.
+	errMissingRegistry = errors.New("no registry defined to push the image to")
+
+	// errMissingContainerBuilder is thrown when no container builder has been found.
+	errMissingContainerBuilder = errors.New("cannot find container builder. Install docker or buildah")
+)
+
+// BuildPipelineOCI builds a container image from a Dockerfile in the
+// source instead of a binary and pushes it to a registry. The build pipeline
+// of the pipeline type is used to prepare the environment.
+type BuildPipelineOCI struct {
+	Type BuildPipeline
+
+	// builder is the path to the container builder.
+	builder string
+}
+
+// PrepareEnvironment prepares the environment of the pipeline type and
+// checks if a container builder is available.
+func (b *BuildPipelineOCI) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	builder, err := containerBuilder()
+	if err != nil {
+		return prepareFailed(err)
+	}
+	b.builder = builder
+	return b.Type.PrepareEnvironment(p)
+}
+
+// containerBuilder returns the path to the preferred installed container builder.
+func containerBuilder() (string, error) {
+	for _, name := range ociBuilders {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errMissingContainerBuilder
+}
+
+// ExecuteBuild builds the container image.
+func (b *BuildPipelineOCI) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	ref, err := imageRef(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Set local destination
+	localDest := ""
+	if p.Pipeline.Repo != nil {
+		localDest = p.Pipeline.Repo.LocalDest
+	}
+
+	// The Dockerfile must be located inside of the repository
+	dockerfile := ociDefaultDockerfile
+	if p.Image.Dockerfile != "" {
+		dockerfile = p.Image.Dockerfile
+	}
+	dockerfilePath := filepath.Join(localDest, filepath.Clean(string(filepath.Separator)+dockerfile))
+	if _, err := os.Stat(dockerfilePath); err != nil {
+		p.Output = fmt.Sprintf("cannot find Dockerfile %s in repository", dockerfile)
+		return compileFailed(err)
+	}
+
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Set command args for build. The proxy is not inherited by the build
+	// container and is passed explicitly.
+	args := []string{
+		"build",
+		"-t",
+		ref,
+		"-f",
+		dockerfilePath,
+	}
+	args = append(args, proxyBuildArgs(env)...)
+	args = append(args, localDest)
+
+	// Execute and wait until finish or timeout
+	output, err := executeBuildCmd(p, phaseCompile, b.builder, args, env, localDest)
+	p.Output = string(output)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot build image", "error", err.Error(), "output", string(output))
+		return compileFailed(err)
+	}
+	p.Pipeline.ImageRef = ref
+	return nil
+}
+
+// CopyBinary pushes the built image to the registry.
+func (b *BuildPipelineOCI) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	env, err := buildEnvironment(p)
+	if err != nil {
+		return copyFailed(err)
+	}
+
+	output, err := executeBuildCmd(p, phaseCompile, b.builder, []string{"push", p.Pipeline.ImageRef}, env, "")
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot push image", "error", err.Error(), "output", string(output))
+		return copyFailed(fmt.Errorf("cannot push image %s: %s", p.Pipeline.ImageRef, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// SavePipeline saves the current pipeline configuration.
+func (b *BuildPipelineOCI) SavePipeline(p *gaia.Pipeline) error {
+	p.Created = time.Now()
+	// Our pipeline is finished constructing. Save it.
+	storeService, _ := services.StorageService()
+	return storeService.PipelinePut(p)
+}
+
+// imageRef returns the reference of the image built for the given pipeline.
+func imageRef(p *gaia.CreatePipeline) (string, error) {
+	registry := p.Image.Registry
+	if registry == "" {
+		registry = gaia.Cfg.ImageRegistry
+	}
+	if registry == "" {
+		return "", errMissingRegistry
+	}
+
+	tag := p.Image.Tag
+	if tag == "" {
+		tag = ociDefaultTag
+	}
+
+	// Image names must be lower case and only contain a limited set of characters
+	name := ociInvalidNameChars.ReplaceAllString(strings.ToLower(p.Pipeline.Name), "-")
+	return fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(registry, "/"), name, tag), nil
+}
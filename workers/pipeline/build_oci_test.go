@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestImageRef(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "My Pipeline"
+	p.Image = &gaia.ImageOptions{}
+	if _, err := imageRef(p); err != errMissingRegistry {
+		t.Fatalf("expected error '%v' but got '%v'", errMissingRegistry, err)
+	}
+
+	gaia.Cfg.ImageRegistry = "registry.example.com/team/"
+	ref, err := imageRef(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != "registry.example.com/team/my-pipeline:latest" {
+		t.Fatalf("unexpected image reference %s", ref)
+	}
+
+	p.Image.Registry = "localhost:5000"
+	p.Image.Tag = "v1"
+	if ref, _ = imageRef(p); ref != "localhost:5000/my-pipeline:v1" {
+		t.Fatalf("unexpected image reference %s", ref)
+	}
+}
+
+func TestExecuteBuildAndPushOCI(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	_ = os.Unsetenv("CMD_ARGS")
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildAndPushOCI")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	b := &BuildPipelineOCI{Type: new(BuildPipelineGolang), builder: "docker"}
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	p.Image = &gaia.ImageOptions{Registry: "localhost:5000"}
+
+	// The Dockerfile is missing
+	if err := b.ExecuteBuild(p); err == nil || !strings.Contains(p.Output, "cannot find Dockerfile") {
+		t.Fatalf("expected missing Dockerfile error but got %v: %s", err, p.Output)
+	}
+
+	_ = ioutil.WriteFile(filepath.Join(tmp, "Dockerfile"), []byte("FROM scratch"), 0600)
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Pipeline.ImageRef != "localhost:5000/main:latest" {
+		t.Fatalf("unexpected image reference %s", p.Pipeline.ImageRef)
+	}
+	if err := b.CopyBinary(p); err != nil {
+		t.Fatal(err)
+	}
+
+	actualArgs := os.Getenv("CMD_ARGS")
+	expectedBuildArgs := "docker,build,-t,localhost:5000/main:latest,-f," + filepath.Join(tmp, "Dockerfile")
+	expectedPushArgs := "docker,push,localhost:5000/main:latest"
+	if !strings.Contains(actualArgs, expectedBuildArgs) || !strings.Contains(actualArgs, expectedPushArgs) {
+		t.Fatalf("expected args '%s' and '%s' actual args '%s'", expectedBuildArgs, expectedPushArgs, actualArgs)
+	}
+}
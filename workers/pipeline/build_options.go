@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"github.com/gaia-pipeline/gaia"
+)
+
+// buildOptions returns the options of the given build request which are
+// stored on the pipeline.
+func buildOptions(p *gaia.CreatePipeline) *gaia.BuildOptions {
+	return &gaia.BuildOptions{
+		Target:           p.Target,
+		Matrix:           p.Matrix,
+		EnvFile:          p.EnvFile,
+		VCS:              p.VCS,
+		SourcePath:       p.SourcePath,
+		Image:            p.Image,
+		RequireCleanTree: p.RequireCleanTree,
+		Replace:          p.Replace,
+		Lint:             p.Lint,
+		GoPlugin:         p.GoPlugin,
+		TinyGo:           p.TinyGo,
+		TinyGoTarget:     p.TinyGoTarget,
+		Artifacts:        p.Artifacts,
+		PreserveSource:   p.PreserveSource,
+		Entrypoint:       p.Entrypoint,
+		SmokeTestFlag:    p.SmokeTestFlag,
+		SmokeTestTimeout: p.SmokeTestTimeout,
+	}
+}
+
+// applyBuildOptions sets the stored build options on the given build
+// request. Nothing is changed if no options have been stored.
+func applyBuildOptions(p *gaia.CreatePipeline, o *gaia.BuildOptions) {
+	if o == nil {
+		return
+	}
+	p.Target = o.Target
+	p.Matrix = o.Matrix
+	p.EnvFile = o.EnvFile
+	p.VCS = o.VCS
+	p.SourcePath = o.SourcePath
+	p.Image = o.Image
+	p.RequireCleanTree = o.RequireCleanTree
+	p.Replace = o.Replace
+	p.Lint = o.Lint
+	p.GoPlugin = o.GoPlugin
+	p.TinyGo = o.TinyGo
+	p.TinyGoTarget = o.TinyGoTarget
+	p.Artifacts = o.Artifacts
+	p.PreserveSource = o.PreserveSource
+	p.Entrypoint = o.Entrypoint
+	p.SmokeTestFlag = o.SmokeTestFlag
+	p.SmokeTestTimeout = o.SmokeTestTimeout
+}
@@ -0,0 +1,238 @@
+package pipeline
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	perlBinaryName  = "perl"
+	cpanmBinaryName = "cpanm"
+
+	// perlInternalCloneFolder is the folder inside of the unique
+	// folder where the sources are cloned to.
+	perlInternalCloneFolder = "perlclone"
+
+	// perlCpanFile defines the dependencies of a perl pipeline.
+	perlCpanFile = "cpanfile"
+
+	// perlLocalLib is the folder dependencies are installed to.
+	perlLocalLib = "local"
+)
+
+// BuildPipelinePerl is the real implementation of BuildPipeline for perl
+type BuildPipelinePerl struct {
+	Type gaia.PipelineType
+}
+
+// PrepareEnvironment prepares the environment before we start the build process.
+func (b *BuildPipelinePerl) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Perl and cpanm are required for the build
+	if err := b.CheckEnvironment(); err != nil {
+		return prepareFailed(err)
+	}
+
+	// create uniqueName for destination folder
+	uniqueName := uuid.Must(uuid.NewV4(), nil)
+
+	// Create local temp folder for clone
+	cloneFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpPerlFolder, srcFolder, uniqueName.String(), perlInternalCloneFolder)
+	err := os.MkdirAll(cloneFolder, 0700)
+	if err != nil {
+		return prepareFailed(err)
+	}
+
+	// Set new generated path in pipeline obj for later usage
+	if p.Pipeline.Repo == nil {
+		p.Pipeline.Repo = &gaia.GitRepo{}
+	}
+	p.Pipeline.Repo.LocalDest = cloneFolder
+	p.Pipeline.UUID = uniqueName.String()
+	p.Pipeline.Entrypoint = p.Entrypoint
+	return nil
+}
+
+// CheckEnvironment checks if the Perl toolchain is installed.
+func (b *BuildPipelinePerl) CheckEnvironment() error {
+	return checkToolchain("Perl", perlBinaryName, cpanmBinaryName)
+}
+
+// ExecuteBuild installs the dependencies, checks the syntax of all perl
+// files and packages the pipeline including the installed dependencies.
+func (b *BuildPipelinePerl) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Look for perl executable
+	perlPath, err := exec.LookPath(perlBinaryName)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot find perl executable", "error", err.Error())
+		return compileFailed(err)
+	}
+
+	// Set local destination
+	localDest := ""
+	if p.Pipeline.Repo != nil {
+		localDest = p.Pipeline.Repo.LocalDest
+	}
+
+	// The configured entrypoint must be part of the sources
+	if err := checkEntrypoint(localDest, p.Pipeline.Entrypoint); err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Set build environment
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Install dependencies into the local lib. Pipelines without
+	// a cpanfile do not have dependencies.
+	var output []byte
+	if _, err := os.Stat(filepath.Join(localDest, perlCpanFile)); err == nil {
+		cpanmPath, err := exec.LookPath(cpanmBinaryName)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot find cpanm executable", "error", err.Error())
+			return compileFailed(err)
+		}
+
+		args := []string{
+			"--installdeps",
+			"--notest",
+			"-L",
+			perlLocalLib,
+			".",
+		}
+		output, err = executeBuildCmd(p, phasePrepare, cpanmPath, args, env, localDest)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot install dependencies", "error", err.Error(), "output", string(output))
+			p.Output = string(output)
+			return compileFailed(err)
+		}
+	}
+
+	// Check syntax of all perl files
+	files, err := findPerlFiles(localDest)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+	for _, file := range files {
+		args := []string{
+			"-Ilib",
+			"-I" + filepath.Join(perlLocalLib, "lib", "perl5"),
+			"-c",
+			file,
+		}
+		out, err := executeBuildCmd(p, phaseCompile, perlPath, args, env, localDest)
+		output = append(output, out...)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("syntax check failed", "error", err.Error(), "file", file, "output", string(out))
+			p.Output = string(output)
+			return compileFailed(err)
+		}
+	}
+
+	// Look for tar executable
+	tarPath, err := exec.LookPath(tarName)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot find tar binary executable", "error", err.Error())
+		return compileFailed(err)
+	}
+
+	// Set command args for archive process
+	pipelineFileName := appendTypeToName(p.Pipeline.Name, p.Pipeline.Type)
+	args := []string{
+		"--exclude=.git",
+		"-czvf",
+		pipelineFileName,
+		"-C",
+		localDest,
+		".",
+	}
+
+	// Execute and wait until finish or timeout
+	uniqueFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpPerlFolder, srcFolder, p.Pipeline.UUID)
+	out, err := executeBuildCmd(p, phaseCompile, tarPath, args, env, uniqueFolder)
+	p.Output = string(append(output, out...))
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot package pipeline", "error", err.Error(), "output", string(out))
+		return compileFailed(err)
+	}
+
+	// Build has been finished. Set execution path to the build result archive.
+	// This will be used during pipeline verification phase which will happen after this step.
+	p.Pipeline.ExecPath = filepath.Join(uniqueFolder, pipelineFileName)
+
+	// Set the the local destination variable to the unique folder because this is now the place
+	// where our binary is located.
+	p.Pipeline.Repo.LocalDest = uniqueFolder
+
+	return nil
+}
+
+// CopyBinary copies the final archive to the destination folder.
+func (b *BuildPipelinePerl) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Define src and destination
+	src := filepath.Join(p.Pipeline.Repo.LocalDest, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
+	dest := binaryDestination(p)
+
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
+}
+
+// SavePipeline saves the current pipeline configuration.
+func (b *BuildPipelinePerl) SavePipeline(p *gaia.Pipeline) error {
+	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
+	p.ExecPath = dest
+	p.Type = gaia.PTypePerl
+	p.Name = filepath.Base(p.Name)
+	p.Created = time.Now()
+	// Our pipeline is finished constructing. Save it.
+	storeService, _ := services.StorageService()
+	return storeService.PipelinePut(p)
+}
+
+// findPerlFiles returns the relative paths of all perl scripts and modules
+// in the given folder. Installed dependencies and the git folder are skipped.
+func findPerlFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == ".git" || rel == perlLocalLib {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext == ".pl" || ext == ".pm" {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+	uuid "github.com/satori/go.uuid"
+)
+
+// fakePerlToolchain puts fake perl, cpanm and tar executables in front of
+// the path. The returned function restores the path.
+func fakePerlToolchain(t *testing.T, dir string) func() {
+	bin := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(bin, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{perlBinaryName, cpanmBinaryName, tarName} {
+		if err := ioutil.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	currentPath := os.Getenv("PATH")
+	_ = os.Setenv("PATH", bin+string(os.PathListSeparator)+currentPath)
+	return func() { _ = os.Setenv("PATH", currentPath) }
+}
+
+func TestPrepareEnvironmentPerl(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPrepareEnvironmentPerl")
+	defer os.RemoveAll(tmp)
+	defer fakePerlToolchain(t, tmp)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	b := new(BuildPipelinePerl)
+	p := new(gaia.CreatePipeline)
+	err := b.PrepareEnvironment(p)
+	if err != nil {
+		t.Fatal("error was not expected when preparing environment: ", err)
+	}
+	var expectedDest = regexp.MustCompile(`^/.*/tmp/perl/src/.*/perlclone$`)
+	if !expectedDest.MatchString(p.Pipeline.Repo.LocalDest) {
+		t.Fatalf("expected destination is '%s', but was '%s'", expectedDest, p.Pipeline.Repo.LocalDest)
+	}
+}
+
+func TestPrepareEnvironmentMissingCpanmPerl(t *testing.T) {
+	currentPath := os.Getenv("PATH")
+	defer func() { _ = os.Setenv("PATH", currentPath) }()
+	_ = os.Setenv("PATH", "")
+	gaia.Cfg = new(gaia.Config)
+	b := new(BuildPipelinePerl)
+	err := b.PrepareEnvironment(new(gaia.CreatePipeline))
+	if err == nil || !strings.Contains(err.Error(), "Perl toolchain not installed") {
+		t.Fatalf("expected missing toolchain error but got: %v", err)
+	}
+}
+
+func TestExecuteBuildPerl(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildPerl")
+	defer os.RemoveAll(tmp)
+	defer fakePerlToolchain(t, tmp)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	pipelineID := uuid.Must(uuid.NewV4(), nil)
+	uniqueFolder := filepath.Join(tmp, gaia.TmpFolder, gaia.TmpPerlFolder, srcFolder, pipelineID.String())
+	cloneFolder := filepath.Join(uniqueFolder, perlInternalCloneFolder)
+	_ = os.MkdirAll(filepath.Join(cloneFolder, "lib"), 0700)
+	_ = os.MkdirAll(filepath.Join(cloneFolder, perlLocalLib), 0700)
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, "main.pl"), []byte("print 1;"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, "lib", "Pipeline.pm"), []byte("1;"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, perlLocalLib, "Dependency.pm"), []byte("1;"), 0600)
+
+	b := new(BuildPipelinePerl)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypePerl
+	p.Pipeline.UUID = pipelineID.String()
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: cloneFolder}
+
+	// Without cpanfile no dependencies are installed
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	actualArgs := os.Getenv("CMD_ARGS")
+	if strings.Contains(actualArgs, "--installdeps") {
+		t.Fatalf("dependencies should not be installed without cpanfile. args: %s", actualArgs)
+	}
+	for _, expected := range []string{"-c,main.pl", "-c,lib/Pipeline.pm", "-czvf,main_perl"} {
+		if !strings.Contains(actualArgs, expected) {
+			t.Fatalf("expected args '%s' actual args '%s'", expected, actualArgs)
+		}
+	}
+	if strings.Contains(actualArgs, "Dependency.pm") {
+		t.Fatalf("installed dependencies should not be checked. args: %s", actualArgs)
+	}
+	if p.Pipeline.ExecPath != filepath.Join(uniqueFolder, "main_perl") {
+		t.Fatalf("unexpected exec path %s", p.Pipeline.ExecPath)
+	}
+
+	// With cpanfile the dependencies are installed into the local lib
+	_ = os.Unsetenv("CMD_ARGS")
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, perlCpanFile), []byte("requires 'JSON';"), 0600)
+	p.Pipeline.Repo.LocalDest = cloneFolder
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	expectedDepArgs := "--installdeps,--notest,-L,local,."
+	if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, expectedDepArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedDepArgs, actualArgs)
+	}
+}
+
+func TestCopyBinaryPerl(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCopyBinaryPerl")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+	b := new(BuildPipelinePerl)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypePerl
+	src := filepath.Join(tmp, "src")
+	_ = os.Mkdir(src, 0700)
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: src}
+	if err := ioutil.WriteFile(filepath.Join(src, "main_perl"), []byte("testcontent"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CopyBinary(p); err != nil {
+		t.Fatal("error was not expected when copying binary: ", err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(tmp, "main_perl"))
+	if err != nil || string(content) != "testcontent" {
+		t.Fatalf("file content did not equal src content. error: %v", err)
+	}
+}
+
+func TestSavePipelinePerl(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = "/tmp/pipelines/"
+	p := new(gaia.Pipeline)
+	p.Name = "main"
+	p.Type = gaia.PTypePerl
+	b := new(BuildPipelinePerl)
+	m := new(nodeJSMockStorer)
+	services.MockStorageService(m)
+	defer services.MockStorageService(nil)
+	if err := b.SavePipeline(p); err != nil {
+		t.Fatal("something went wrong. wasn't supposed to get error: ", err)
+	}
+	if p.Name != "main" || p.Type != gaia.PTypePerl || p.ExecPath != "/tmp/pipelines/main_perl" {
+		t.Fatalf("unexpected saved pipeline: %+v", p)
+	}
+}
@@ -25,6 +25,10 @@ type BuildPipelinePython struct {
 
 // PrepareEnvironment prepares the environment before we start the build process.
 func (b *BuildPipelinePython) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// create uniqueName for destination folder
 	uniqueName := uuid.Must(uuid.NewV4(), nil)
 
@@ -33,7 +37,7 @@ func (b *BuildPipelinePython) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	cloneFolder := filepath.Join(rootPath, srcFolder, uniqueName.String())
 	err := os.MkdirAll(cloneFolder, 0700)
 	if err != nil {
-		return err
+		return prepareFailed(err)
 	}
 
 	// Set new generated path in pipeline obj for later usage
@@ -45,13 +49,22 @@ func (b *BuildPipelinePython) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	return nil
 }
 
+// CheckEnvironment checks if the Python toolchain is installed.
+func (b *BuildPipelinePython) CheckEnvironment() error {
+	return checkToolchain("Python", pythonBinaryName)
+}
+
 // ExecuteBuild executes the python build process
 func (b *BuildPipelinePython) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Look for python executeable
 	path, err := exec.LookPath(pythonBinaryName)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot find python executeable", "error", err.Error())
-		return err
+		return compileFailed(err)
 	}
 
 	// Set command args for build distribution package
@@ -66,19 +79,26 @@ func (b *BuildPipelinePython) ExecuteBuild(p *gaia.CreatePipeline) error {
 		localDest = p.Pipeline.Repo.LocalDest
 	}
 
+	// Set build environment
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
 	// Execute and wait until finish or timeout
-	output, err := executeCmd(path, args, os.Environ(), localDest)
+	output, err := executeBuildCmd(p, phaseCompile, path, args, env, localDest)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot generate python distribution package", "error", err.Error(), "output", string(output))
 		p.Output = string(output)
-		return err
+		return compileFailed(err)
 	}
 
 	// Build has been finished. Set execution path to the build result archive.
 	// This will be used during pipeline verification phase which will happen after this step.
 	p.Pipeline.ExecPath, err = findPythonArchivePath(p)
 	if err != nil {
-		return err
+		return compileFailed(err)
 	}
 
 	return nil
@@ -118,20 +138,19 @@ func findPythonArchivePath(p *gaia.CreatePipeline) (src string, err error) {
 // CopyBinary copies the final compiled archive to the
 // destination folder.
 func (b *BuildPipelinePython) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Define src and destination
 	src, err := findPythonArchivePath(p)
 	if err != nil {
-		return err
-	}
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-
-	// Copy binary
-	if err := copyFileContents(src, dest); err != nil {
-		return err
+		return copyFailed(err)
 	}
+	dest := binaryDestination(p)
 
-	// Set +x (execution right) for pipeline
-	return os.Chmod(dest, gaia.ExecutablePermission)
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
 }
 
 // SavePipeline saves the current pipeline configuration.
@@ -139,7 +158,7 @@ func (b *BuildPipelinePython) SavePipeline(p *gaia.Pipeline) error {
 	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
 	p.ExecPath = dest
 	p.Type = gaia.PTypePython
-	p.Name = strings.TrimSuffix(filepath.Base(dest), typeDelimiter+gaia.PTypePython.String())
+	p.Name = filepath.Base(p.Name)
 	p.Created = time.Now()
 	// Our pipeline is finished constructing. Save it.
 	storeService, _ := services.StorageService()
@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+)
+
+// defaultBuildWorker is the number of concurrent build workers
+// used when no number has been configured.
+const defaultBuildWorker = 2
+
+// defaultBuildQueueSize is the maximum number of pending builds
+// used when no size has been configured.
+const defaultBuildQueueSize = 100
+
+// ErrBuildQueueFull is returned when a build is submitted but the
+// maximum number of pending builds has been reached.
+var ErrBuildQueueFull = errors.New("build queue is full")
+
+// buildQueue is a FIFO queue of pipelines which are waiting to be built.
+// The queue is processed by a fixed number of build workers.
+type buildQueue struct {
+	sync.Mutex
+	cond *sync.Cond
+
+	// pending holds all builds which have not been started yet.
+	pending []*gaia.CreatePipeline
+
+	// running holds the number of running builds per pipeline name.
+	running map[string]int
+
+	// paused stops pending builds from being started.
+	paused bool
+
+	// build executes a single build.
+	build func(*gaia.CreatePipeline)
+
+	startWorker sync.Once
+}
+
+// globalBuildQueue holds globally all submitted builds.
+var globalBuildQueue = newBuildQueue(CreatePipeline)
+
+// newBuildQueue creates a new build queue which executes builds
+// with the given build function.
+func newBuildQueue(build func(*gaia.CreatePipeline)) *buildQueue {
+	q := &buildQueue{
+		pending: make([]*gaia.CreatePipeline, 0),
+		running: make(map[string]int),
+		build:   build,
+	}
+	q.cond = sync.NewCond(q)
+	return q
+}
+
+// SubmitBuild adds the given pipeline to the build queue.
+// The build is started as soon as a build worker is available.
+// ErrBuildQueueFull is returned if the build queue is full.
+func SubmitBuild(p *gaia.CreatePipeline) error {
+	return globalBuildQueue.submit(p)
+}
+
+// QueueDepth returns the number of builds which are waiting for a build worker.
+func QueueDepth() int {
+	return globalBuildQueue.depth()
+}
+
+// QueuePosition returns the position of the pipeline with the given name
+// in the build queue. The first pending build has the position 1. A build
+// which is already running has the position 0. The second return value
+// is false if there is neither a pending nor a running build.
+func QueuePosition(name string) (int, bool) {
+	return globalBuildQueue.position(name)
+}
+
+// PauseBuilds stops pending builds from being started, e.g. for the
+// maintenance of the build host. Builds can still be submitted and
+// running builds are finished.
+func PauseBuilds() {
+	globalBuildQueue.pause()
+}
+
+// ResumeBuilds starts the pending builds again after PauseBuilds.
+func ResumeBuilds() {
+	globalBuildQueue.resume()
+}
+
+// BuildsPaused returns true if builds have been paused.
+func BuildsPaused() bool {
+	return globalBuildQueue.isPaused()
+}
+
+// submit adds the given pipeline to the queue and starts the build
+// workers if they are not running yet. The build is rejected if the
+// maximum number of pending builds has been reached.
+func (q *buildQueue) submit(p *gaia.CreatePipeline) error {
+	q.startWorker.Do(func() {
+		worker := gaia.Cfg.BuildWorker
+		if worker < 1 {
+			worker = defaultBuildWorker
+		}
+		for i := 0; i < worker; i++ {
+			go q.work()
+		}
+	})
+
+	size := gaia.Cfg.BuildQueueSize
+	if size < 1 {
+		size = defaultBuildQueueSize
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	if len(q.pending) >= size {
+		return ErrBuildQueueFull
+	}
+	q.pending = append(q.pending, p)
+	q.cond.Signal()
+	return nil
+}
+
+// pause stops the build workers from taking pending builds.
+func (q *buildQueue) pause() {
+	q.Lock()
+	defer q.Unlock()
+	q.paused = true
+}
+
+// resume wakes up the build workers after pause.
+func (q *buildQueue) resume() {
+	q.Lock()
+	defer q.Unlock()
+	q.paused = false
+	q.cond.Broadcast()
+}
+
+// isPaused returns true if the queue has been paused.
+func (q *buildQueue) isPaused() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.paused
+}
+
+// depth returns the number of pending builds.
+func (q *buildQueue) depth() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.pending)
+}
+
+// position returns the queue position of the pipeline with the given name.
+func (q *buildQueue) position(name string) (int, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.running[name] > 0 {
+		return 0, true
+	}
+	for i, p := range q.pending {
+		if p.Pipeline.Name == name {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// next blocks until a pending build is available and the queue is not
+// paused and marks the build as running.
+func (q *buildQueue) next() *gaia.CreatePipeline {
+	q.Lock()
+	defer q.Unlock()
+
+	for q.paused || len(q.pending) == 0 {
+		q.cond.Wait()
+	}
+	p := q.pending[0]
+	q.pending = q.pending[1:]
+	q.running[p.Pipeline.Name]++
+	startBuildContext(p)
+	return p
+}
+
+// remove removes all pending builds of the pipeline with the given name
+// and returns them.
+func (q *buildQueue) remove(name string) []*gaia.CreatePipeline {
+	q.Lock()
+	defer q.Unlock()
+
+	var removed []*gaia.CreatePipeline
+	pending := q.pending[:0]
+	for _, p := range q.pending {
+		if p.Pipeline.Name == name {
+			removed = append(removed, p)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+	q.pending = pending
+	return removed
+}
+
+// done marks the build of the given pipeline as finished.
+func (q *buildQueue) done(p *gaia.CreatePipeline) {
+	finishBuildContext(p)
+
+	q.Lock()
+	defer q.Unlock()
+
+	q.running[p.Pipeline.Name]--
+	if q.running[p.Pipeline.Name] <= 0 {
+		delete(q.running, p.Pipeline.Name)
+	}
+	if p.IdempotencyKey != "" {
+		finishIdempotencyKey(p.Pipeline.Name, p.IdempotencyKey)
+	}
+}
+
+// work processes pending builds until the process ends.
+func (q *buildQueue) work() {
+	for {
+		q.run(q.next())
+	}
+}
+
+// run executes the given build and marks it as finished. A panic of the
+// build fails the build instead of stopping the build worker.
+func (q *buildQueue) run(p *gaia.CreatePipeline) {
+	defer q.done(p)
+	defer func() {
+		if r := recover(); r != nil {
+			gaia.Cfg.Logger.Error("build of pipeline panicked", "pipeline", p.Pipeline.Name, "panic", r)
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("build failed unexpectedly: %v", r)
+			storeService, _ := services.StorageService()
+			_ = storeService.CreatePipelinePut(p)
+		}
+	}()
+	q.build(p)
+}
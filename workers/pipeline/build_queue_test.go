@@ -0,0 +1,219 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestQueuePosition(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildWorker = 1
+
+	// Builds are blocked until they get released
+	started := make(chan string)
+	release := make(chan struct{})
+	q := newBuildQueue(func(p *gaia.CreatePipeline) {
+		started <- p.Pipeline.Name
+		<-release
+	})
+
+	for _, name := range []string{"first", "second", "third"} {
+		p := new(gaia.CreatePipeline)
+		p.Pipeline.Name = name
+		q.submit(p)
+	}
+	if name := <-started; name != "first" {
+		t.Fatalf("expected build 'first' to start but got '%s'", name)
+	}
+
+	expected := map[string]int{"first": 0, "second": 1, "third": 2}
+	for name, pos := range expected {
+		if p, ok := q.position(name); !ok || p != pos {
+			t.Fatalf("expected position %d for '%s' but got %d (queued: %v)", pos, name, p, ok)
+		}
+	}
+	if _, ok := q.position("unknown"); ok {
+		t.Fatal("unknown pipeline should not be queued")
+	}
+
+	// Finish the first build, all positions must move up
+	release <- struct{}{}
+	if name := <-started; name != "second" {
+		t.Fatalf("expected build 'second' to start but got '%s'", name)
+	}
+	if p, ok := q.position("third"); !ok || p != 1 {
+		t.Fatalf("expected position 1 for 'third' but got %d (queued: %v)", p, ok)
+	}
+
+	// Finish all builds
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	timeout := time.After(5 * time.Second)
+	for {
+		if _, ok := q.position("third"); !ok {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatal("build 'third' is still reported after it has been finished")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if _, ok := q.position("first"); ok {
+		t.Fatal("finished build should not be queued")
+	}
+}
+
+func TestBuildQueueFull(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildWorker = 1
+	gaia.Cfg.BuildQueueSize = 2
+
+	started := make(chan string)
+	release := make(chan struct{})
+	q := newBuildQueue(func(p *gaia.CreatePipeline) {
+		started <- p.Pipeline.Name
+		<-release
+	})
+	submit := func(name string) error {
+		p := new(gaia.CreatePipeline)
+		p.Pipeline.Name = name
+		return q.submit(p)
+	}
+
+	// The running build does not count as pending
+	if err := submit("running"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	for _, name := range []string{"first", "second"} {
+		if err := submit(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := q.depth(); d != 2 {
+		t.Fatalf("expected queue depth 2 but got %d", d)
+	}
+	if err := submit("third"); err != ErrBuildQueueFull {
+		t.Fatalf("expected error '%v' but got '%v'", ErrBuildQueueFull, err)
+	}
+
+	// A build can be submitted again once the queue drained
+	release <- struct{}{}
+	<-started
+	if err := submit("third"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		release <- struct{}{}
+		if i < 2 {
+			<-started
+		}
+	}
+}
+
+func TestPauseBuilds(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildWorker = 1
+
+	started := make(chan string)
+	release := make(chan struct{})
+	q := newBuildQueue(func(p *gaia.CreatePipeline) {
+		started <- p.Pipeline.Name
+		<-release
+	})
+	submit := func(name string) {
+		p := new(gaia.CreatePipeline)
+		p.Pipeline.Name = name
+		if err := q.submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The running build is finished while the queue is paused
+	submit("running")
+	<-started
+	q.pause()
+	if !q.isPaused() {
+		t.Fatal("expected queue to be paused")
+	}
+	submit("queued")
+	release <- struct{}{}
+	select {
+	case name := <-started:
+		t.Fatalf("expected no build to start while paused but '%s' started", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if p, ok := q.position("queued"); !ok || p != 1 {
+		t.Fatalf("expected position 1 for 'queued' but got %d (queued: %v)", p, ok)
+	}
+
+	q.resume()
+	select {
+	case name := <-started:
+		if name != "queued" {
+			t.Fatalf("expected build 'queued' to start but got '%s'", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected pending build to start after resume")
+	}
+	release <- struct{}{}
+}
+
+func TestBuildQueuePanic(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildWorker = 1
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	services.MockStorageService(new(mockCreatePipelineStore))
+	defer services.MockStorageService(nil)
+
+	// The first build panics, the second one reports that it has been started
+	started := make(chan string, 1)
+	q := newBuildQueue(func(p *gaia.CreatePipeline) {
+		if p.Pipeline.Name == "panic" {
+			panic("boom")
+		}
+		started <- p.Pipeline.Name
+	})
+
+	key := "TestBuildQueuePanic"
+	if _, ok := ClaimIdempotencyKey("panic", key, "panic"); !ok {
+		t.Fatal("expected idempotency key to be claimed")
+	}
+	defer ReleaseIdempotencyKey("panic", key)
+	panicking := &gaia.CreatePipeline{IdempotencyKey: key}
+	panicking.Pipeline.Name = "panic"
+	_ = q.submit(panicking)
+	next := new(gaia.CreatePipeline)
+	next.Pipeline.Name = "next"
+	_ = q.submit(next)
+
+	// The build worker survives the panic
+	select {
+	case name := <-started:
+		if name != "next" {
+			t.Fatalf("expected build 'next' to start but got '%s'", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("build worker stopped after the panic")
+	}
+	if _, ok := q.position("panic"); ok {
+		t.Fatal("panicked build should not be reported as running")
+	}
+	if panicking.StatusType != gaia.CreatePipelineFailed || !strings.Contains(panicking.Output, "boom") {
+		t.Fatalf("expected failed build but got %s: %s", panicking.StatusType, panicking.Output)
+	}
+	idempotentBuildsLock.Lock()
+	finished := !idempotentBuilds[idempotencyScope("panic", key)].finished.IsZero()
+	idempotentBuildsLock.Unlock()
+	if !finished {
+		t.Fatal("expected idempotency key of the panicked build to be finished")
+	}
+}
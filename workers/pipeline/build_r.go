@@ -0,0 +1,250 @@
+package pipeline
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	rscriptBinaryName = "Rscript"
+
+	// rInternalCloneFolder is the folder inside of the unique
+	// folder where the sources are cloned to.
+	rInternalCloneFolder = "rclone"
+
+	// rLockFile pins the dependencies of a R pipeline with renv.
+	rLockFile = "renv.lock"
+
+	// rDescriptionFile defines the dependencies of a R package.
+	rDescriptionFile = "DESCRIPTION"
+
+	// rLibrary is the folder dependencies are installed to.
+	rLibrary = "library"
+
+	// rNoDependenciesWarning is added to the build output if the
+	// pipeline does not define its dependencies.
+	rNoDependenciesWarning = "warning: neither renv.lock nor DESCRIPTION found. The pipeline is packaged without dependencies\n"
+)
+
+// rInstallCommands are the R expressions which install the dependencies
+// into the library folder by dependency file.
+var rInstallCommands = map[string]string{
+	rLockFile:        "renv::restore(library = '" + rLibrary + "', prompt = FALSE)",
+	rDescriptionFile: "dir.create('" + rLibrary + "', showWarnings = FALSE); remotes::install_deps('.', lib = '" + rLibrary + "')",
+}
+
+// BuildPipelineR is the real implementation of BuildPipeline for R
+type BuildPipelineR struct {
+	Type gaia.PipelineType
+}
+
+// PrepareEnvironment prepares the environment before we start the build process.
+func (b *BuildPipelineR) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Rscript is required for the build
+	if err := b.CheckEnvironment(); err != nil {
+		return prepareFailed(err)
+	}
+
+	// create uniqueName for destination folder
+	uniqueName := uuid.Must(uuid.NewV4(), nil)
+
+	// Create local temp folder for clone
+	cloneFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpRFolder, srcFolder, uniqueName.String(), rInternalCloneFolder)
+	err := os.MkdirAll(cloneFolder, 0700)
+	if err != nil {
+		return prepareFailed(err)
+	}
+
+	// Set new generated path in pipeline obj for later usage
+	if p.Pipeline.Repo == nil {
+		p.Pipeline.Repo = &gaia.GitRepo{}
+	}
+	p.Pipeline.Repo.LocalDest = cloneFolder
+	p.Pipeline.UUID = uniqueName.String()
+	p.Pipeline.Entrypoint = p.Entrypoint
+	return nil
+}
+
+// CheckEnvironment checks if the R toolchain is installed.
+func (b *BuildPipelineR) CheckEnvironment() error {
+	return checkToolchain("R", rscriptBinaryName)
+}
+
+// ExecuteBuild installs the dependencies, validates the syntax of all R
+// scripts and packages the pipeline including the installed dependencies.
+func (b *BuildPipelineR) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Look for Rscript executable
+	rscriptPath, err := exec.LookPath(rscriptBinaryName)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot find Rscript executable", "error", err.Error())
+		return compileFailed(err)
+	}
+
+	// Set local destination
+	localDest := ""
+	if p.Pipeline.Repo != nil {
+		localDest = p.Pipeline.Repo.LocalDest
+	}
+
+	// The configured entrypoint must be part of the sources
+	if err := checkEntrypoint(localDest, p.Pipeline.Entrypoint); err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Set build environment
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
+	// Install dependencies into the library. The lockfile takes precedence
+	// over the package description. Pipelines without either of them are
+	// packaged without dependencies.
+	var output []byte
+	installed := false
+	for _, file := range []string{rLockFile, rDescriptionFile} {
+		if _, err := os.Stat(filepath.Join(localDest, file)); err != nil {
+			continue
+		}
+		args := []string{"-e", rInstallCommands[file]}
+		output, err = executeBuildCmd(p, phasePrepare, rscriptPath, args, env, localDest)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot install dependencies", "error", err.Error(), "output", string(output))
+			p.Output = string(output)
+			return compileFailed(err)
+		}
+		installed = true
+		break
+	}
+	if !installed {
+		gaia.Cfg.Logger.Warn("no dependency file found for R pipeline", "pipeline", p.Pipeline.Name)
+		output = []byte(rNoDependenciesWarning)
+	}
+
+	// Validate syntax of all R scripts
+	files, err := findRFiles(localDest)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+	for _, file := range files {
+		args := []string{
+			"-e",
+			"invisible(parse(file = commandArgs(TRUE)[1]))",
+			file,
+		}
+		out, err := executeBuildCmd(p, phaseCompile, rscriptPath, args, env, localDest)
+		output = append(output, out...)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("syntax check failed", "error", err.Error(), "file", file, "output", string(out))
+			p.Output = string(output)
+			return compileFailed(err)
+		}
+	}
+
+	// Look for tar executable
+	tarPath, err := exec.LookPath(tarName)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot find tar binary executable", "error", err.Error())
+		return compileFailed(err)
+	}
+
+	// Set command args for archive process
+	pipelineFileName := appendTypeToName(p.Pipeline.Name, p.Pipeline.Type)
+	args := []string{
+		"--exclude=.git",
+		"-czvf",
+		pipelineFileName,
+		"-C",
+		localDest,
+		".",
+	}
+
+	// Execute and wait until finish or timeout
+	uniqueFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpRFolder, srcFolder, p.Pipeline.UUID)
+	out, err := executeBuildCmd(p, phaseCompile, tarPath, args, env, uniqueFolder)
+	p.Output = string(append(output, out...))
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot package pipeline", "error", err.Error(), "output", string(out))
+		return compileFailed(err)
+	}
+
+	// Build has been finished. Set execution path to the build result archive.
+	// This will be used during pipeline verification phase which will happen after this step.
+	p.Pipeline.ExecPath = filepath.Join(uniqueFolder, pipelineFileName)
+
+	// Set the the local destination variable to the unique folder because this is now the place
+	// where our binary is located.
+	p.Pipeline.Repo.LocalDest = uniqueFolder
+
+	return nil
+}
+
+// CopyBinary copies the final archive to the destination folder.
+func (b *BuildPipelineR) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
+	// Define src and destination
+	src := filepath.Join(p.Pipeline.Repo.LocalDest, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
+	dest := binaryDestination(p)
+
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
+}
+
+// SavePipeline saves the current pipeline configuration.
+func (b *BuildPipelineR) SavePipeline(p *gaia.Pipeline) error {
+	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
+	p.ExecPath = dest
+	p.Type = gaia.PTypeR
+	p.Name = filepath.Base(p.Name)
+	p.Created = time.Now()
+	// Our pipeline is finished constructing. Save it.
+	storeService, _ := services.StorageService()
+	return storeService.PipelinePut(p)
+}
+
+// findRFiles returns the relative paths of all R scripts in the given
+// folder. Installed dependencies, the renv folder and the git folder are skipped.
+func findRFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == ".git" || rel == rLibrary || rel == "renv" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.EqualFold(filepath.Ext(path), ".r") {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
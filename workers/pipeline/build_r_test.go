@@ -0,0 +1,178 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+	uuid "github.com/satori/go.uuid"
+)
+
+// fakeRToolchain puts fake Rscript and tar executables in front of
+// the path. The returned function restores the path.
+func fakeRToolchain(t *testing.T, dir string) func() {
+	bin := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(bin, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{rscriptBinaryName, tarName} {
+		if err := ioutil.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	currentPath := os.Getenv("PATH")
+	_ = os.Setenv("PATH", bin+string(os.PathListSeparator)+currentPath)
+	return func() { _ = os.Setenv("PATH", currentPath) }
+}
+
+func TestPrepareEnvironmentR(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPrepareEnvironmentR")
+	defer os.RemoveAll(tmp)
+	defer fakeRToolchain(t, tmp)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	b := new(BuildPipelineR)
+	p := new(gaia.CreatePipeline)
+	err := b.PrepareEnvironment(p)
+	if err != nil {
+		t.Fatal("error was not expected when preparing environment: ", err)
+	}
+	var expectedDest = regexp.MustCompile(`^/.*/tmp/r/src/.*/rclone$`)
+	if !expectedDest.MatchString(p.Pipeline.Repo.LocalDest) {
+		t.Fatalf("expected destination is '%s', but was '%s'", expectedDest, p.Pipeline.Repo.LocalDest)
+	}
+}
+
+func TestPrepareEnvironmentMissingRscript(t *testing.T) {
+	currentPath := os.Getenv("PATH")
+	defer func() { _ = os.Setenv("PATH", currentPath) }()
+	_ = os.Setenv("PATH", "")
+	gaia.Cfg = new(gaia.Config)
+	b := new(BuildPipelineR)
+	err := b.PrepareEnvironment(new(gaia.CreatePipeline))
+	if err == nil || !strings.Contains(err.Error(), "R toolchain not installed") {
+		t.Fatalf("expected missing toolchain error but got: %v", err)
+	}
+}
+
+func TestExecuteBuildR(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildR")
+	defer os.RemoveAll(tmp)
+	defer fakeRToolchain(t, tmp)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	pipelineID := uuid.Must(uuid.NewV4(), nil)
+	uniqueFolder := filepath.Join(tmp, gaia.TmpFolder, gaia.TmpRFolder, srcFolder, pipelineID.String())
+	cloneFolder := filepath.Join(uniqueFolder, rInternalCloneFolder)
+	_ = os.MkdirAll(filepath.Join(cloneFolder, "R"), 0700)
+	_ = os.MkdirAll(filepath.Join(cloneFolder, rLibrary), 0700)
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, "main.R"), []byte("print(1)"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, "R", "helper.r"), []byte("f <- function() 1"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(cloneFolder, rLibrary, "dependency.R"), []byte("1"), 0600)
+
+	b := new(BuildPipelineR)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeR
+	p.Pipeline.UUID = pipelineID.String()
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: cloneFolder}
+
+	// Without dependency file the pipeline is packaged with a warning
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	actualArgs := os.Getenv("CMD_ARGS")
+	if strings.Contains(actualArgs, "install_deps") || strings.Contains(actualArgs, "renv::restore") {
+		t.Fatalf("dependencies should not be installed without dependency file. args: %s", actualArgs)
+	}
+	for _, expected := range []string{"[1])),main.R", "[1])),R/helper.r", "-czvf,main_r"} {
+		if !strings.Contains(actualArgs, expected) {
+			t.Fatalf("expected args '%s' actual args '%s'", expected, actualArgs)
+		}
+	}
+	if strings.Contains(actualArgs, "dependency.R") {
+		t.Fatalf("installed dependencies should not be checked. args: %s", actualArgs)
+	}
+	if !strings.HasPrefix(p.Output, rNoDependenciesWarning) {
+		t.Fatalf("expected missing dependencies warning in output but got: %s", p.Output)
+	}
+	if p.Pipeline.ExecPath != filepath.Join(uniqueFolder, "main_r") {
+		t.Fatalf("unexpected exec path %s", p.Pipeline.ExecPath)
+	}
+
+	// The lockfile takes precedence over the description
+	for _, dep := range []struct{ file, command string }{
+		{rDescriptionFile, "remotes::install_deps"},
+		{rLockFile, "renv::restore"},
+	} {
+		file, command := dep.file, dep.command
+		_ = os.Unsetenv("CMD_ARGS")
+		_ = ioutil.WriteFile(filepath.Join(cloneFolder, file), []byte("{}"), 0600)
+		p.Pipeline.Repo.LocalDest = cloneFolder
+		if err := b.ExecuteBuild(p); err != nil {
+			t.Fatal(err)
+		}
+		if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, command) {
+			t.Fatalf("expected args '%s' actual args '%s'", command, actualArgs)
+		}
+		if strings.Contains(p.Output, rNoDependenciesWarning) {
+			t.Fatalf("unexpected missing dependencies warning in output: %s", p.Output)
+		}
+	}
+}
+
+func TestCopyBinaryR(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCopyBinaryR")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+	b := new(BuildPipelineR)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeR
+	src := filepath.Join(tmp, "src")
+	_ = os.Mkdir(src, 0700)
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: src}
+	if err := ioutil.WriteFile(filepath.Join(src, "main_r"), []byte("testcontent"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CopyBinary(p); err != nil {
+		t.Fatal("error was not expected when copying binary: ", err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(tmp, "main_r"))
+	if err != nil || string(content) != "testcontent" {
+		t.Fatalf("file content did not equal src content. error: %v", err)
+	}
+}
+
+func TestSavePipelineR(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = "/tmp/pipelines/"
+	p := new(gaia.Pipeline)
+	p.Name = "main"
+	p.Type = gaia.PTypeR
+	b := new(BuildPipelineR)
+	m := new(nodeJSMockStorer)
+	services.MockStorageService(m)
+	defer services.MockStorageService(nil)
+	if err := b.SavePipeline(p); err != nil {
+		t.Fatal("something went wrong. wasn't supposed to get error: ", err)
+	}
+	if p.Name != "main" || p.Type != gaia.PTypeR || p.ExecPath != "/tmp/pipelines/main_r" {
+		t.Fatalf("unexpected saved pipeline: %+v", p)
+	}
+}
@@ -31,6 +31,10 @@ type BuildPipelineRuby struct {
 
 // PrepareEnvironment prepares the environment before we start the build process.
 func (b *BuildPipelineRuby) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// create uniqueName for destination folder
 	uniqueName := uuid.Must(uuid.NewV4(), nil)
 
@@ -38,7 +42,7 @@ func (b *BuildPipelineRuby) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	cloneFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpRubyFolder, srcFolder, uniqueName.String())
 	err := os.MkdirAll(cloneFolder, 0700)
 	if err != nil {
-		return err
+		return prepareFailed(err)
 	}
 
 	// Set new generated path in pipeline obj for later usage
@@ -50,13 +54,22 @@ func (b *BuildPipelineRuby) PrepareEnvironment(p *gaia.CreatePipeline) error {
 	return nil
 }
 
+// CheckEnvironment checks if the Ruby toolchain is installed.
+func (b *BuildPipelineRuby) CheckEnvironment() error {
+	return checkToolchain("Ruby", gemBinaryName)
+}
+
 // ExecuteBuild executes the ruby build process
 func (b *BuildPipelineRuby) ExecuteBuild(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Look for gem binary executable
 	path, err := exec.LookPath(gemBinaryName)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot find gem binary executable", "error", err.Error())
-		return err
+		return compileFailed(err)
 	}
 
 	// Set local destination
@@ -65,17 +78,24 @@ func (b *BuildPipelineRuby) ExecuteBuild(p *gaia.CreatePipeline) error {
 		localDest = p.Pipeline.Repo.LocalDest
 	}
 
+	// Set build environment
+	env, err := buildEnvironment(p)
+	if err != nil {
+		p.Output = err.Error()
+		return compileFailed(err)
+	}
+
 	// Get all gemspec files in cloned folder.
 	gemspec, err := filterPathContentBySuffix(localDest, ".gemspec")
 	if err != nil {
 		gaia.Cfg.Logger.Error("cannot find gemspec file in cloned repository folder", "path", localDest)
-		return err
+		return compileFailed(err)
 	}
 
 	// if we found more or less than one gemspec we have a problem.
 	if len(gemspec) != 1 {
 		gaia.Cfg.Logger.Debug("cannot find gemspec file in cloned repo", "foundGemspecs", len(gemspec), "gemspecs", gemspec)
-		return errors.New("cannot find gemspec file in cloned repo")
+		return compileFailed(errors.New("cannot find gemspec file in cloned repo"))
 	}
 
 	// Generate a new UUID for the gem name to prevent conflicts with other gems.
@@ -85,7 +105,7 @@ func (b *BuildPipelineRuby) ExecuteBuild(p *gaia.CreatePipeline) error {
 	gemspecContent, err := ioutil.ReadFile(gemspec[0])
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot read gemspec file", "error", err.Error(), "pipeline", p.Pipeline.Name)
-		return err
+		return compileFailed(err)
 	}
 
 	// Replace name variable with new UUID and write content to file.
@@ -93,7 +113,7 @@ func (b *BuildPipelineRuby) ExecuteBuild(p *gaia.CreatePipeline) error {
 	err = ioutil.WriteFile(gemspec[0], []byte(gemspecContentStr), 0644)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot write/edit gemspec file", "error", err.Error(), "pipeline", p.Pipeline.Name)
-		return err
+		return compileFailed(err)
 	}
 
 	// The initial ruby file in the gem must be named like the gem name.
@@ -101,7 +121,7 @@ func (b *BuildPipelineRuby) ExecuteBuild(p *gaia.CreatePipeline) error {
 	err = os.Rename(filepath.Join(localDest, "lib", gemInitFile), filepath.Join(localDest, "lib", uuid+".rb"))
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot rename initial ruby file", "error", err.Error(), "pipeline", p.Pipeline)
-		return err
+		return compileFailed(err)
 	}
 
 	// Set command args for build
@@ -111,24 +131,24 @@ func (b *BuildPipelineRuby) ExecuteBuild(p *gaia.CreatePipeline) error {
 	}
 
 	// Execute and wait until finish or timeout
-	output, err := executeCmd(path, args, os.Environ(), localDest)
+	output, err := executeBuildCmd(p, phaseCompile, path, args, env, localDest)
 	p.Output = string(output)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot build pipeline", "error", err.Error(), "output", string(output))
-		return err
+		return compileFailed(err)
 	}
 
 	// Search for resulting gem file.
 	gemfile, err := filterPathContentBySuffix(localDest, ".gem")
 	if err != nil {
 		gaia.Cfg.Logger.Error("cannot find final gem file after build", "path", p.Pipeline.Repo.LocalDest)
-		return err
+		return compileFailed(err)
 	}
 
 	// if we found more or less than one gem file then we have a problem.
 	if len(gemfile) != 1 {
 		gaia.Cfg.Logger.Debug("cannot find gem file in cloned repo", "foundGemFiles", len(gemfile), "gems", gemfile)
-		return errors.New("cannot find gem file in cloned repo")
+		return compileFailed(errors.New("cannot find gem file in cloned repo"))
 	}
 
 	// Build has been finished. Set execution path to the build result archive.
@@ -161,24 +181,23 @@ func filterPathContentBySuffix(path, suffix string) ([]string, error) {
 // CopyBinary copies the final compiled binary to the
 // destination folder.
 func (b *BuildPipelineRuby) CopyBinary(p *gaia.CreatePipeline) error {
+	if p == nil {
+		return errNilCreatePipeline
+	}
+
 	// Search for resulting gem file.
 	gemfile, err := filterPathContentBySuffix(p.Pipeline.Repo.LocalDest, ".gem")
 	if err != nil {
 		gaia.Cfg.Logger.Error("cannot find final gem file during copy", "path", p.Pipeline.Repo.LocalDest)
-		return err
+		return copyFailed(err)
 	}
 
 	// Define src and destination
 	src := gemfile[0]
-	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Pipeline.Name, p.Pipeline.Type))
-
-	// Copy binary
-	if err := copyFileContents(src, dest); err != nil {
-		return err
-	}
+	dest := binaryDestination(p)
 
-	// Set +x (execution right) for pipeline
-	return os.Chmod(dest, gaia.ExecutablePermission)
+	// Copy binary and set +x (execution right) for pipeline
+	return copyFailed(installBinary(src, dest))
 }
 
 // SavePipeline saves the current pipeline configuration.
@@ -186,7 +205,7 @@ func (b *BuildPipelineRuby) SavePipeline(p *gaia.Pipeline) error {
 	dest := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
 	p.ExecPath = dest
 	p.Type = gaia.PTypeRuby
-	p.Name = strings.TrimSuffix(filepath.Base(dest), typeDelimiter+gaia.PTypeRuby.String())
+	p.Name = filepath.Base(p.Name)
 	p.Created = time.Now()
 	// Our pipeline is finished constructing. Save it.
 	storeService, _ := services.StorageService()
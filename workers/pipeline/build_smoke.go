@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// defaultSmokeTestTimeout is the time the binary gets for the smoke test
+// if no timeout has been configured.
+const defaultSmokeTestTimeout = 10 * time.Second
+
+// smokeTestTypes are the pipeline types which are built into a binary
+// which can be started directly.
+var smokeTestTypes = map[gaia.PipelineType]bool{
+	gaia.PTypeGolang:  true,
+	gaia.PTypeCpp:     true,
+	gaia.PTypeHaskell: true,
+}
+
+// validateSmokeTest checks if the smoke test of the given pipeline can run.
+// The binary must be an executable for this host.
+func validateSmokeTest(p *gaia.CreatePipeline) error {
+	if p.SmokeTestFlag == "" {
+		return nil
+	}
+	if !smokeTestTypes[p.Pipeline.Type] {
+		return fmt.Errorf("smoke test is not supported for %s pipelines", p.Pipeline.Type)
+	}
+	if p.SmokeTestTimeout < 0 {
+		return fmt.Errorf("smoke test timeout must not be negative")
+	}
+	crossCompiled := p.Target != nil && (p.Target.OS != hostOS || p.Target.Arch != runtime.GOARCH)
+	if p.Image != nil || p.GoPlugin || p.TinyGoTarget != "" || crossCompiled {
+		return fmt.Errorf("smoke test requires a binary which runs on this host")
+	}
+	return nil
+}
+
+// runSmokeTest starts the built binary of the given pipeline with the
+// smoke test flag. An error is returned if the binary does not exit zero
+// within the timeout.
+func runSmokeTest(p *gaia.CreatePipeline) error {
+	if p.SmokeTestFlag == "" {
+		return nil
+	}
+	timeout := time.Duration(p.SmokeTestTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultSmokeTestTimeout
+	}
+
+	binary := p.Pipeline.ExecPath
+	output, err := runCmdWithTimeout(buildContext(p), p.Pipeline.Name, buildLogWriter(p.Pipeline.Name), phaseSmokeTest, timeout, nil, binary, []string{p.SmokeTestFlag}, os.Environ(), filepath.Dir(binary))
+	if err != nil {
+		gaia.Cfg.Logger.Debug("smoke test of pipeline failed", "pipeline", p.Pipeline.Name, "error", err.Error(), "output", string(output))
+		return fmt.Errorf("smoke test %s %s failed: %s\n%s", filepath.Base(binary), p.SmokeTestFlag, err.Error(), string(output))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestValidateSmokeTest(t *testing.T) {
+	p := &gaia.CreatePipeline{SmokeTestFlag: "--version"}
+	p.Pipeline.Type = gaia.PTypePython
+	if err := validateSmokeTest(p); err == nil || !strings.Contains(err.Error(), "not supported for python") {
+		t.Fatalf("expected unsupported type error but got %v", err)
+	}
+
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Target = &gaia.BuildTarget{OS: "plan9", Arch: "arm"}
+	if err := validateSmokeTest(p); err == nil || !strings.Contains(err.Error(), "runs on this host") {
+		t.Fatalf("expected cross compilation error but got %v", err)
+	}
+	p.Target = &gaia.BuildTarget{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if err := validateSmokeTest(p); err != nil {
+		t.Fatal(err)
+	}
+	p.SmokeTestTimeout = -1
+	if err := validateSmokeTest(p); err == nil {
+		t.Fatal("expected negative timeout to be rejected")
+	}
+}
+
+func TestRunSmokeTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("smoke test binaries are shell scripts")
+	}
+	tmp, _ := ioutil.TempDir("", "TestRunSmokeTest")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	binary := filepath.Join(tmp, "main_golang")
+	p := &gaia.CreatePipeline{SmokeTestFlag: "--healthcheck", SmokeTestTimeout: 1}
+	p.Pipeline.Name = "main"
+	p.Pipeline.ExecPath = binary
+
+	// The binary must get the flag and exit zero
+	_ = ioutil.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = \"--healthcheck\" ]\n"), 0700)
+	if err := runSmokeTest(p); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = ioutil.WriteFile(binary, []byte("#!/bin/sh\necho panic: crashed\nexit 2\n"), 0700)
+	err := runSmokeTest(p)
+	if err == nil || !strings.Contains(err.Error(), "smoke test main_golang --healthcheck failed") || !strings.Contains(err.Error(), "panic: crashed") {
+		t.Fatalf("expected smoke test failure with output but got %v", err)
+	}
+
+	_ = ioutil.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 5\n"), 0700)
+	if err := runSmokeTest(p); err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Fatalf("expected smoke test timeout but got %v", err)
+	}
+
+	// Without a flag no smoke test is run
+	p.SmokeTestFlag = ""
+	if err := runSmokeTest(p); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// runningBuild represents the in-flight builds of a single pipeline.
+type runningBuild struct {
+	// lock serializes the builds of the pipeline.
+	lock sync.Mutex
+
+	// refs is the number of in-flight builds of the pipeline.
+	refs int
+}
+
+// buildTracker keeps track of all in-flight builds.
+type buildTracker struct {
+	sync.Mutex
+
+	builds map[string]*runningBuild
+}
+
+// runningBuilds holds globally all in-flight builds.
+var runningBuilds = &buildTracker{
+	builds: make(map[string]*runningBuild),
+}
+
+// acquireBuildLock marks a build of the given pipeline as in-flight and
+// blocks until no other build of the same pipeline is running.
+// The returned function must be called when the build has been finished.
+func acquireBuildLock(name string) func() {
+	b := trackBuild(name)
+	b.lock.Lock()
+
+	return func() {
+		b.lock.Unlock()
+		untrackBuild(name, b)
+	}
+}
+
+// trackBuild marks a build of the given pipeline as in-flight without
+// waiting for other builds of the pipeline. The build must be untracked
+// with untrackBuild when it has been finished.
+func trackBuild(name string) *runningBuild {
+	runningBuilds.Lock()
+	defer runningBuilds.Unlock()
+
+	b, ok := runningBuilds.builds[name]
+	if !ok {
+		b = &runningBuild{}
+		runningBuilds.builds[name] = b
+
+		// Write the output of all build commands to the build log
+		if err := openBuildLog(name); err != nil {
+			gaia.Cfg.Logger.Error("cannot create build log", "pipeline", name, "error", err.Error())
+		}
+	}
+	b.refs++
+	return b
+}
+
+// untrackBuild marks a build of the given pipeline as finished.
+func untrackBuild(name string, b *runningBuild) {
+	runningBuilds.Lock()
+	defer runningBuilds.Unlock()
+
+	b.refs--
+	if b.refs == 0 {
+		delete(runningBuilds.builds, name)
+		closeBuildLog(name)
+	}
+}
+
+// IsBuilding returns true if a build of the pipeline with the given name
+// is currently in progress.
+func IsBuilding(name string) bool {
+	runningBuilds.Lock()
+	defer runningBuilds.Unlock()
+
+	_, ok := runningBuilds.builds[name]
+	return ok
+}
+
+// ListRunningBuilds returns the sorted names of all pipelines which
+// are currently built.
+func ListRunningBuilds() []string {
+	runningBuilds.Lock()
+	defer runningBuilds.Unlock()
+
+	names := make([]string, 0, len(runningBuilds.builds))
+	for name := range runningBuilds.builds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
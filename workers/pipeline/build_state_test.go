@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireBuildLock(t *testing.T) {
+	if IsBuilding("PipelineA") {
+		t.Fatal("PipelineA should not be building")
+	}
+
+	release := acquireBuildLock("PipelineA")
+	if !IsBuilding("PipelineA") {
+		t.Fatal("PipelineA should be building")
+	}
+	releaseB := acquireBuildLock("PipelineB")
+	running := ListRunningBuilds()
+	if len(running) != 2 || running[0] != "PipelineA" || running[1] != "PipelineB" {
+		t.Fatalf("expected PipelineA and PipelineB to be running. got: %v", running)
+	}
+	releaseB()
+
+	// A second build of the same pipeline must wait for the first one
+	acquired := make(chan func())
+	go func() {
+		acquired <- acquireBuildLock("PipelineA")
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second build should wait until the first build has been finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case releaseSecond := <-acquired:
+		if !IsBuilding("PipelineA") {
+			t.Fatal("PipelineA should still be building")
+		}
+		releaseSecond()
+	case <-time.After(time.Second):
+		t.Fatal("second build should have acquired the lock")
+	}
+
+	if IsBuilding("PipelineA") {
+		t.Fatal("PipelineA should not be building anymore")
+	}
+	if len(ListRunningBuilds()) != 0 {
+		t.Fatalf("expected no running builds. got: %v", ListRunningBuilds())
+	}
+}
@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	tinyGoBinaryName = "tinygo"
+
+	// tinyGoLimitation explains failed TinyGo builds. Most of them are caused
+	// by packages of the standard library which TinyGo does not support.
+	tinyGoLimitation = "\nThe pipeline could not be compiled by TinyGo. TinyGo does not support " +
+		"all packages of the go standard library, see https://tinygo.org/docs/reference/lang-support/stdlib/"
+)
+
+// checkTinyGoMode checks if the go pipeline can be compiled with TinyGo.
+func checkTinyGoMode(p *gaia.CreatePipeline) error {
+	if p.GoPlugin {
+		return errors.New("go plugin modules cannot be built with TinyGo")
+	}
+	if p.Target != nil || len(p.Matrix) > 0 {
+		return errors.New("TinyGo builds are configured by the TinyGo target")
+	}
+	return checkToolchain("TinyGo", tinyGoBinaryName)
+}
+
+// isTinyGoWasmTarget checks if TinyGo builds for the given TinyGo target
+// produce WebAssembly binaries, e.g. "wasm", "wasi" or "wasip2".
+func isTinyGoWasmTarget(target string) bool {
+	return strings.HasPrefix(target, "wasm") || strings.HasPrefix(target, "wasi")
+}
+
+// tinyGoBuildArgs returns the arguments of the TinyGo build command.
+func tinyGoBuildArgs(p *gaia.CreatePipeline) []string {
+	args := []string{"build"}
+	if p.TinyGoTarget != "" {
+		args = append(args, "-target", p.TinyGoTarget)
+	}
+	return append(args, "-o", golangOutputName(p), ".")
+}
+
+// tinyGoBuildFailed marks the given compile error as TinyGo failure
+// so users can tell TinyGo limitations from errors of Gaia.
+// Timeouts and cancelled builds are left as they are.
+func tinyGoBuildFailed(p *gaia.CreatePipeline, err error) error {
+	var timeoutErr *buildTimeoutError
+	if errors.As(err, &timeoutErr) || errors.Is(err, errBuildCancelled) {
+		return err
+	}
+	p.Output += tinyGoLimitation
+	return fmt.Errorf("tinygo build failed: %w", err)
+}
@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// fakeToolchain creates empty executables with the given names and
+// makes them the only binaries on the PATH.
+func fakeToolchain(t *testing.T, dir string, binaries ...string) func() {
+	for _, binary := range binaries {
+		if err := ioutil.WriteFile(filepath.Join(dir, binary), nil, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	currentPath := os.Getenv("PATH")
+	_ = os.Setenv("PATH", dir)
+	return func() { _ = os.Setenv("PATH", currentPath) }
+}
+
+func TestCheckTinyGoMode(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCheckTinyGoMode")
+	defer os.RemoveAll(tmp)
+	defer fakeToolchain(t, tmp)()
+
+	p := &gaia.CreatePipeline{TinyGo: true}
+	if err := checkTinyGoMode(p); err == nil || !strings.Contains(err.Error(), "cannot find tinygo") {
+		t.Fatalf("expected missing toolchain error but got %v", err)
+	}
+	p.GoPlugin = true
+	if err := checkTinyGoMode(p); err == nil || !strings.Contains(err.Error(), "plugin") {
+		t.Fatalf("expected plugin module error but got %v", err)
+	}
+	p.GoPlugin = false
+	p.Target = &gaia.BuildTarget{OS: "linux", Arch: "arm"}
+	if err := checkTinyGoMode(p); err == nil || !strings.Contains(err.Error(), "TinyGo target") {
+		t.Fatalf("expected target error but got %v", err)
+	}
+}
+
+func TestExecuteBuildTinyGo(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildTinyGo")
+	defer os.RemoveAll(tmp)
+	defer fakeToolchain(t, tmp, golangBinaryName, tinyGoBinaryName)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	b := new(BuildPipelineGolang)
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "main"
+	p.Pipeline.Type = gaia.PTypeGolang
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: filepath.Join(tmp, "src")}
+	p.TinyGo = true
+	p.TinyGoTarget = "wasi"
+	_ = os.MkdirAll(p.Pipeline.Repo.LocalDest, 0700)
+
+	_ = os.Unsetenv("CMD_ARGS")
+	if err := b.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	expectedArgs := filepath.Join(tmp, tinyGoBinaryName) + ",build,-target,wasi,-o,main_golang.wasm,."
+	if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, expectedArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedArgs, actualArgs)
+	}
+
+	// The WebAssembly binary keeps its extension so it is started in the WebAssembly runtime
+	if filepath.Base(p.Pipeline.ExecPath) != "main_golang.wasm" {
+		t.Fatalf("expected WebAssembly exec path but got %s", p.Pipeline.ExecPath)
+	}
+	gaia.Cfg.PipelinePath = tmp
+	if dest := binaryDestination(p); dest != filepath.Join(tmp, "main_golang.wasm") {
+		t.Fatalf("expected WebAssembly binary in the pipelines folder but got %s", dest)
+	}
+	if execPath := GetExecPath(p.Pipeline); execPath != filepath.Join(tmp, "main_golang.wasm") {
+		t.Fatalf("expected exec path of the installed WebAssembly binary but got %s", execPath)
+	}
+}
+
+func TestIsTinyGoWasmTarget(t *testing.T) {
+	for target, expected := range map[string]bool{"wasm": true, "wasi": true, "wasip2": true, "arduino": false, "": false} {
+		if isTinyGoWasmTarget(target) != expected {
+			t.Fatalf("expected %v for TinyGo target '%s'", expected, target)
+		}
+	}
+}
+
+func TestTinyGoBuildFailed(t *testing.T) {
+	p := &gaia.CreatePipeline{Output: "package net/http is not in std"}
+	err := tinyGoBuildFailed(p, errors.New("exit status 1"))
+	if err == nil || !strings.Contains(err.Error(), "tinygo build failed") {
+		t.Fatalf("expected TinyGo error but got %v", err)
+	}
+	if !strings.HasPrefix(p.Output, "package net/http is not in std") || !strings.Contains(p.Output, "TinyGo does not support") {
+		t.Fatalf("expected TinyGo hint in output but got %q", p.Output)
+	}
+
+	// Timeouts are not caused by TinyGo
+	p.Output = ""
+	timeout := &buildTimeoutError{phase: phaseCompile}
+	if err := tinyGoBuildFailed(p, timeout); err != timeout || p.Output != "" {
+		t.Fatalf("expected timeout to be kept but got %v with output %q", err, p.Output)
+	}
+}
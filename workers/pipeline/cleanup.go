@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// buildFolder returns the unique temporary folder of the given build.
+// The unique folder is named after the pipeline UUID and contains all
+// sources and intermediate build results.
+func buildFolder(p *gaia.CreatePipeline) string {
+	if p.Pipeline.Repo == nil || p.Pipeline.Repo.LocalDest == "" || p.Pipeline.UUID == "" {
+		return ""
+	}
+
+	// Some build pipelines clone into a sub folder of the unique folder.
+	for dir := filepath.Clean(p.Pipeline.Repo.LocalDest); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == p.Pipeline.UUID {
+			return dir
+		}
+	}
+	return ""
+}
+
+// cleanupBuildFolder removes the temporary build folder of the given
+// pipeline dependent on the configured cleanup policy. By default, the
+// build folder is only removed after a successful build. Otherwise it
+// is retained to allow inspection of what the compiler saw.
+func cleanupBuildFolder(p *gaia.CreatePipeline) {
+	folder := buildFolder(p)
+	if folder == "" {
+		return
+	}
+
+	success := p.StatusType == gaia.CreatePipelineSuccess || p.StatusType == gaia.CreatePipelineUpToDate
+	switch gaia.Cfg.CleanupPolicy {
+	case gaia.CleanupNever:
+		return
+	case gaia.CleanupAlways:
+	default:
+		if !success {
+			gaia.Cfg.Logger.Info("build failed. retained build folder for inspection", "pipeline", p.Pipeline.Name, "path", folder)
+			return
+		}
+	}
+
+	if err := removeBuildFolder(folder); err != nil {
+		gaia.Cfg.Logger.Error("cannot remove build folder", "error", err.Error(), "path", folder)
+	}
+}
+
+// removeBuildFolder removes the given build folder. The folder must not be
+// a symlink and must be located inside of the Gaia temp folder, even after
+// all symlinks in its path have been resolved. Symlinks inside the folder
+// are removed but never followed.
+func removeBuildFolder(folder string) error {
+	info, err := os.Lstat(folder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		return fmt.Errorf("build folder is not a directory")
+	}
+
+	tmpFolder, err := filepath.EvalSymlinks(filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder))
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(folder)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(tmpFolder, resolved)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("build folder is located outside of the temp folder %s", tmpFolder)
+	}
+
+	// RemoveAll removes symlinks itself and not the files they point to.
+	return os.RemoveAll(resolved)
+}
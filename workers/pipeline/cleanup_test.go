@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func createTestBuildFolder(t *testing.T, root string) *gaia.CreatePipeline {
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.UUID = "a3d2b6d0-8cc6-4ffb-8f6a-1b5e2c72a43c"
+	uniqueFolder := filepath.Join(root, gaia.TmpFolder, srcFolder, p.Pipeline.UUID)
+	cloneFolder := filepath.Join(uniqueFolder, nodeJSInternalCloneFolder)
+	if err := os.MkdirAll(cloneFolder, 0700); err != nil {
+		t.Fatal(err)
+	}
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: cloneFolder}
+	return p
+}
+
+func TestBuildFolder(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestBuildFolder")
+	defer os.RemoveAll(tmp)
+	p := createTestBuildFolder(t, tmp)
+	expected := filepath.Join(tmp, gaia.TmpFolder, srcFolder, p.Pipeline.UUID)
+	if folder := buildFolder(p); folder != expected {
+		t.Fatalf("expected build folder %s but got %s", expected, folder)
+	}
+
+	p.Pipeline.UUID = ""
+	if folder := buildFolder(p); folder != "" {
+		t.Fatalf("expected no build folder without uuid but got %s", folder)
+	}
+}
+
+func TestCleanupBuildFolder(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCleanupBuildFolder")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	buf := new(bytes.Buffer)
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.Trace,
+		Output: buf,
+		Name:   "Gaia",
+	})
+
+	tests := []struct {
+		policy   gaia.CleanupPolicy
+		status   gaia.CreatePipelineType
+		retained bool
+	}{
+		{"", gaia.CreatePipelineSuccess, false},
+		{"", gaia.CreatePipelineFailed, true},
+		{"", gaia.CreatePipelineUpToDate, false},
+		{gaia.CleanupOnSuccess, gaia.CreatePipelineFailed, true},
+		{gaia.CleanupAlways, gaia.CreatePipelineFailed, false},
+		{gaia.CleanupNever, gaia.CreatePipelineSuccess, true},
+	}
+	for _, test := range tests {
+		gaia.Cfg.CleanupPolicy = test.policy
+		p := createTestBuildFolder(t, tmp)
+		p.StatusType = test.status
+		folder := buildFolder(p)
+		cleanupBuildFolder(p)
+		_, err := os.Stat(folder)
+		if test.retained && err != nil {
+			t.Fatalf("build folder should have been retained for policy '%s' and status '%s'", test.policy, test.status)
+		}
+		if !test.retained && !os.IsNotExist(err) {
+			t.Fatalf("build folder should have been removed for policy '%s' and status '%s'", test.policy, test.status)
+		}
+	}
+
+	// The retained folder must be logged for failed builds
+	if !strings.Contains(buf.String(), "retained build folder for inspection") {
+		t.Fatalf("expected retained build folder to be logged. was: %s", buf.String())
+	}
+}
+
+func TestCleanupBuildFolderSymlinks(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCleanupBuildFolderSymlinks")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = filepath.Join(tmp, "home")
+	gaia.Cfg.CleanupPolicy = gaia.CleanupAlways
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	// The real source tree is located outside of the temp folder
+	realSource := filepath.Join(tmp, "source")
+	realFile := filepath.Join(realSource, "main.go")
+	_ = os.MkdirAll(realSource, 0700)
+	_ = ioutil.WriteFile(realFile, []byte("package main"), 0600)
+
+	// A symlink inside the build folder escapes the temp folder
+	p := createTestBuildFolder(t, gaia.Cfg.HomePath)
+	folder := buildFolder(p)
+	if err := os.Symlink(realSource, filepath.Join(p.Pipeline.Repo.LocalDest, "source")); err != nil {
+		t.Fatal(err)
+	}
+	cleanupBuildFolder(p)
+	if _, err := os.Stat(folder); !os.IsNotExist(err) {
+		t.Fatal("build folder should have been removed")
+	}
+	if _, err := os.Stat(realFile); err != nil {
+		t.Fatalf("symlink target should not have been touched: %s", err.Error())
+	}
+
+	// The build folder itself is a symlink to the real source tree
+	p = createTestBuildFolder(t, gaia.Cfg.HomePath)
+	folder = buildFolder(p)
+	_ = os.RemoveAll(folder)
+	if err := os.Symlink(realSource, folder); err != nil {
+		t.Fatal(err)
+	}
+	cleanupBuildFolder(p)
+	if _, err := os.Stat(realFile); err != nil {
+		t.Fatalf("symlink target should not have been touched: %s", err.Error())
+	}
+}
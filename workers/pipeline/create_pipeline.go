@@ -3,7 +3,10 @@ package pipeline
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/gaia-pipeline/gaia/security"
 
@@ -26,14 +29,33 @@ const (
 
 	// Split char to separate path from pipeline and name
 	pipelinePathSplitChar = "/"
+
+	// Max length of a single path element of a pipeline name
+	maxPipelineNameLength = 50
+
+	// Characters which are not allowed in file names on all platforms
+	invalidPipelineNameChars = `\:*?"<>|`
 )
 
 var (
+	// errPipelineNameEmpty is a validation error during pipeline name input
+	errPipelineNameEmpty = errors.New("name of pipeline is empty")
+
+	// errPathElementEmpty is thrown when the pipeline name contains an empty path element
+	errPathElementEmpty = errors.New("path elements of the pipeline name must not be empty. check for leading, trailing or double slashes")
+
 	// errPathLength is a validation error during pipeline name input
 	errPathLength = errors.New("name of pipeline is empty or one of the path elements length exceeds 50 characters")
 
+	// errPathElementReserved is thrown when a path element is a relative path reference
+	errPathElementReserved = errors.New("path elements of the pipeline name must not be '.' or '..'")
+
 	// errPipelineNameInUse is thrown when a pipelines name is already in use
 	errPipelineNameInUse = errors.New("pipeline name is already in use")
+
+	// errPipelineNameDelimiter is thrown when the pipeline name contains the
+	// delimiter which separates the name from the type in the binary name
+	errPipelineNameDelimiter = fmt.Errorf("pipeline name must not contain %q", typeDelimiter)
 )
 
 // CreatePipeline is the main function which executes step by step the creation
@@ -53,6 +75,67 @@ func CreatePipeline(p *gaia.CreatePipeline) {
 		return
 	}
 
+	// Build a container image instead of a binary if requested
+	if p.Image != nil {
+		bP = &BuildPipelineOCI{Type: bP}
+	}
+
+	// Mark the build as in-flight. Builds of the same pipeline are
+	// serialized once their sources have been hashed.
+	build := trackBuild(p.Pipeline.Name)
+	defer untrackBuild(p.Pipeline.Name, build)
+
+	// Record the build in the build history and notify about the build
+	// result when we are done. Builds which join an identical build in
+	// progress are recorded and notified by that build.
+	started := time.Now()
+	shared := false
+	defer func() {
+		if shared {
+			return
+		}
+		notifyBuild(p)
+		recordBuild(p, started)
+	}()
+
+	// Remove the temporary build folder when we are done
+	defer cleanupBuildFolder(p)
+
+	// Validate the build matrix before we start any work
+	if err := validateBuildMatrix(p); err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot prepare build: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+	if err := validatePathFilters(p); err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot prepare build: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+	// Updates of the repository are filtered and built like this build
+	p.Pipeline.PathFilters = p.PathFilters
+	p.Pipeline.BuildOptions = buildOptions(p)
+	if err := validateArtifacts(p); err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot prepare build: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+	if err := validateEntrypoint(p); err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot prepare build: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+	if err := validateSmokeTest(p); err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot prepare build: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+
 	// Setup environment before cloning repo and command
 	err := bP.PrepareEnvironment(p)
 	if err != nil {
@@ -62,8 +145,8 @@ func CreatePipeline(p *gaia.CreatePipeline) {
 		return
 	}
 
-	// Clone git repo
-	err = gitCloneRepo(p.Pipeline.Repo)
+	// Clone git repo or copy the local sources
+	err = prepareFailed(acquireSource(p))
 	if err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
 		p.Output = fmt.Sprintf("cannot prepare build: %s", err.Error())
@@ -71,25 +154,87 @@ func CreatePipeline(p *gaia.CreatePipeline) {
 		return
 	}
 
-	// Update status of our pipeline build
-	p.Status = pipelineCloneStatus
-	err = storeService.CreatePipelinePut(p)
+	// Transform the sources before they are hashed and compiled
+	err = prepareFailed(transformSource(p))
 	if err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
-		gaia.Cfg.Logger.Error("cannot put create pipeline into store", "error", err.Error())
+		p.Output = fmt.Sprintf("cannot transform sources: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
 		return
 	}
 
-	// Run compile process
-	err = bP.ExecuteBuild(p)
+	// Attach the metadata of the manifest in the source root
+	p.Pipeline.Metadata, err = readManifest(p.Pipeline.Repo.LocalDest)
 	if err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot read pipeline manifest: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+
+	// Hash the sources before the build writes into the source folder
+	sourceHash, err := hashSourceTree(p.Pipeline.Repo.LocalDest)
+	if err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot hash sources: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+
+	// Identical builds which are in progress share a single build
+	key, err := buildFlightKey(p, sourceHash)
+	if err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot hash build options: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+	flight, leader := joinBuildFlight(key)
+	if !leader {
+		shared = true
+		flight.wait(p)
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+	defer flight.finish(p)
+
+	// Prevent concurrent builds of the same pipeline
+	build.lock.Lock()
+	defer build.lock.Unlock()
+
+	// Skip the build if none of the filtered paths has changed
+	if pathFiltersUnchanged(p) {
+		p.Status = pipelineCompleteStatus
+		p.StatusType = gaia.CreatePipelineUpToDate
+		p.Output = "no changes in the filtered paths, reused existing binary"
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+
+	// Reuse the existing binary if nothing changed since the last build
+	if upToDate(p, sourceHash) {
+		p.Status = pipelineCompleteStatus
+		p.StatusType = gaia.CreatePipelineUpToDate
+		p.Output = "no changes, reused existing binary"
 		_ = storeService.CreatePipelinePut(p)
 		return
 	}
 
+	// Snapshot the sources before the build writes into the source folder
+	var sourceArchive string
+	if p.PreserveSource && p.Image == nil {
+		sourceArchive, err = archiveSource(p.Pipeline.Repo.LocalDest)
+		if err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot archive sources: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+		defer os.Remove(sourceArchive)
+	}
+
 	// Update status of our pipeline build
-	p.Status = pipelineCompileStatus
+	p.Status = pipelineCloneStatus
 	err = storeService.CreatePipelinePut(p)
 	if err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
@@ -97,24 +242,76 @@ func CreatePipeline(p *gaia.CreatePipeline) {
 		return
 	}
 
-	// Run update if needed
-	err = updatePipeline(&p.Pipeline)
+	// Run compile process. NodeJS, Perl and R builds move the local
+	// destination to the build folder, artifacts live in the sources.
+	sourceRoot := p.Pipeline.Repo.LocalDest
+	err = bP.ExecuteBuild(p)
 	if err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
-		p.Output = fmt.Sprintf("cannot update pipeline: %s", err.Error())
 		_ = storeService.CreatePipelinePut(p)
 		return
 	}
 
-	// Try to get pipeline jobs to check if this pipeline is valid.
-	schedulerService, _ := services.SchedulerService()
-	if err = schedulerService.SetPipelineJobs(&p.Pipeline); err != nil {
+	// Start the binary once before it gets promoted
+	if err = runSmokeTest(p); err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
-		p.Output = fmt.Sprintf("cannot validate pipeline: %s", err.Error())
+		p.Output = err.Error()
 		_ = storeService.CreatePipelinePut(p)
 		return
 	}
 
+	// Every declared artifact must exist after the build
+	var artifacts []string
+	if len(p.Artifacts) > 0 {
+		artifacts, err = findArtifacts(sourceRoot, p.Artifacts)
+		if err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot find artifacts: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+	}
+
+	// Record the inputs and the output of the build
+	p.Pipeline.Fingerprint, err = fingerprintBuild(p, sourceHash)
+	if err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		p.Output = fmt.Sprintf("cannot fingerprint build: %s", err.Error())
+		_ = storeService.CreatePipelinePut(p)
+		return
+	}
+
+	// Update status of our pipeline build
+	p.Status = pipelineCompileStatus
+	err = storeService.CreatePipelinePut(p)
+	if err != nil {
+		p.StatusType = gaia.CreatePipelineFailed
+		gaia.Cfg.Logger.Error("cannot put create pipeline into store", "error", err.Error())
+		return
+	}
+
+	// Images are not executed locally and plugin modules are loaded
+	// in-process. Both cannot be started to validate them.
+	if p.Image == nil && !p.Pipeline.PluginModule {
+		// Run update if needed
+		err = updatePipeline(&p.Pipeline)
+		if err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot update pipeline: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+
+		// Try to get pipeline jobs to check if this pipeline is valid.
+		schedulerService, _ := services.SchedulerService()
+		if err = schedulerService.SetPipelineJobs(&p.Pipeline); err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot validate pipeline: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+	}
+
 	// Update status of our pipeline build
 	p.Status = pipelineValidateStatus
 	err = storeService.CreatePipelinePut(p)
@@ -143,6 +340,47 @@ func CreatePipeline(p *gaia.CreatePipeline) {
 		return
 	}
 
+	// Store name and type next to the binary
+	if p.Image == nil {
+		if err = writeBinaryMetadata(binaryDestination(p), p.Pipeline); err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot write pipeline metadata: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+	}
+
+	// Store the source archive next to the binary. An archive of a
+	// previous build does not match the new binary anymore.
+	if sourceArchive != "" {
+		if err = installBinary(sourceArchive, binaryDestination(p)+sourceArchiveSuffix); err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot store source archive: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+	} else if p.Image == nil {
+		_ = os.Remove(binaryDestination(p) + sourceArchiveSuffix)
+	}
+
+	// Store the artifacts next to the binary. Artifacts of a previous
+	// build do not match the new binary anymore.
+	if p.Image == nil {
+		err = copyFailed(installArtifacts(sourceRoot, artifacts, binaryDestination(p)))
+		if err != nil {
+			p.StatusType = gaia.CreatePipelineFailed
+			p.Output = fmt.Sprintf("cannot copy artifacts: %s", err.Error())
+			_ = storeService.CreatePipelinePut(p)
+			return
+		}
+	}
+
+	// Compile the pipeline for all additionally requested targets.
+	// Failed entries are reported per entry and do not fail the pipeline.
+	if len(p.Matrix) > 0 {
+		p.MatrixResults = executeBuildMatrix(bP, p)
+	}
+
 	// Set create pipeline status to complete
 	p.Status = pipelineCompleteStatus
 	p.StatusType = gaia.CreatePipelineSuccess
@@ -163,25 +401,59 @@ func CreatePipeline(p *gaia.CreatePipeline) {
 	}
 }
 
-// ValidatePipelineName validates a given pipeline name and
-// returns the correct error back.
+// ValidatePipelineName validates a given pipeline name against the naming
+// rules of pipeline binaries and returns an error which describes all
+// violated rules. The name may contain a path separated by slashes. Every
+// path element must be a valid file name and the name must not be in use.
 func ValidatePipelineName(pName string) error {
+	if pName == "" {
+		return errPipelineNameEmpty
+	}
+
+	// Every violated rule is reported once
+	var violations []error
+	violate := func(err error) {
+		for _, v := range violations {
+			if v.Error() == err.Error() {
+				return
+			}
+		}
+		violations = append(violations, err)
+	}
+
 	// The name could contain a path. Split it up.
 	path := strings.Split(pName, pipelinePathSplitChar)
 
 	// Iterate all objects.
 	for _, s := range path {
 		// Length should be correct.
-		if len(s) < 1 || len(s) > 50 {
-			return errPathLength
+		if len(s) < 1 {
+			violate(errPathElementEmpty)
+			continue
+		}
+		if len(s) > maxPipelineNameLength {
+			violate(errPathLength)
+		}
+		if s == "." || s == ".." {
+			violate(errPathElementReserved)
+		}
+
+		// The name becomes part of the binary file name
+		for _, r := range s {
+			if unicode.IsControl(r) || strings.ContainsRune(invalidPipelineNameChars, r) {
+				violate(fmt.Errorf("pipeline name contains the invalid character %q", r))
+			}
+		}
+		if strings.Contains(s, typeDelimiter) {
+			violate(errPipelineNameDelimiter)
 		}
 
 		// Check if pipeline name is already in use.
-		for _, activePipeline := range GlobalActivePipelines.GetAll() {
+		for _, activePipeline := range GetGlobalActivePipelines().GetAll() {
 			if strings.ToLower(s) == strings.ToLower(activePipeline.Name) {
-				return errPipelineNameInUse
+				violate(errPipelineNameInUse)
 			}
 		}
 	}
-	return nil
+	return errors.Join(violations...)
 }
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -163,3 +166,103 @@ func TestCreatePipelineSetPipelineJobsFail(t *testing.T) {
 		t.Fatalf("error thrown should contain 'cannot validate pipeline' but its %s", cp.Output)
 	}
 }
+
+func TestValidatePipelineName(t *testing.T) {
+	active := GetGlobalActivePipelines()
+	defer SetGlobalActivePipelines(active)
+	SetGlobalActivePipelines(NewActivePipelines())
+	GetGlobalActivePipelines().Append(gaia.Pipeline{Name: "existing"})
+
+	valid := []string{"Pipeline A", "my-pipeline", "group/pipeline-1", strings.Repeat("a", maxPipelineNameLength)}
+	for _, name := range valid {
+		if err := ValidatePipelineName(name); err != nil {
+			t.Fatalf("expected %q to be valid but got %v", name, err)
+		}
+	}
+
+	invalid := []struct {
+		name string
+		err  string
+	}{
+		{"", errPipelineNameEmpty.Error()},
+		{"group//pipeline", errPathElementEmpty.Error()},
+		{"pipeline/", errPathElementEmpty.Error()},
+		{strings.Repeat("a", maxPipelineNameLength+1), errPathLength.Error()},
+		{"../pipeline", errPathElementReserved.Error()},
+		{"pipe:line", `invalid character ':'`},
+		{"pipe\nline", `invalid character '\n'`},
+		{"Existing", errPipelineNameInUse.Error()},
+		{"my_pipeline", errPipelineNameDelimiter.Error()},
+	}
+	for _, c := range invalid {
+		if err := ValidatePipelineName(c.name); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected error %q for %q but got %v", c.err, c.name, err)
+		}
+	}
+
+	// All violated rules are reported once
+	err := ValidatePipelineName("my_pipe:line//" + strings.Repeat("a", maxPipelineNameLength+1))
+	for _, target := range []error{errPipelineNameDelimiter, errPathElementEmpty, errPathLength} {
+		if !errors.Is(err, target) {
+			t.Fatalf("expected error %q but got %v", target, err)
+		}
+	}
+	if !strings.Contains(err.Error(), `invalid character ':'`) {
+		t.Fatalf("expected invalid character error but got %v", err)
+	}
+	if err := ValidatePipelineName("a//b//c"); err == nil || strings.Count(err.Error(), errPathElementEmpty.Error()) != 1 {
+		t.Fatalf("expected empty path element error once but got %v", err)
+	}
+}
+
+func TestCreatePipelineArtifactsPerl(t *testing.T) {
+	for _, name := range []string{perlBinaryName, tarName} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+	tmp, _ := ioutil.TempDir("", "TestCreatePipelineArtifactsPerl")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = filepath.Join(tmp, "pipelines")
+	_ = os.MkdirAll(gaia.Cfg.PipelinePath, 0700)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	mcp := new(mockCreatePipelineStore)
+	services.MockStorageService(mcp)
+	defer func() { services.MockStorageService(nil) }()
+	ms := new(mockScheduler)
+	services.MockSchedulerService(ms)
+	defer func() { services.MockSchedulerService(nil) }()
+
+	// Pipelines without a cpanfile do not run cpanm
+	bin := filepath.Join(tmp, "bin")
+	_ = os.MkdirAll(bin, 0700)
+	_ = ioutil.WriteFile(filepath.Join(bin, cpanmBinaryName), nil, 0700)
+	currentPath := os.Getenv("PATH")
+	_ = os.Setenv("PATH", bin+string(os.PathListSeparator)+currentPath)
+	defer os.Setenv("PATH", currentPath)
+
+	src := filepath.Join(tmp, "src")
+	_ = os.MkdirAll(filepath.Join(src, "templates"), 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, "pipeline.pl"), []byte("1;\n"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(src, "templates", "index.tmpl"), []byte("index"), 0600)
+
+	cp := new(gaia.CreatePipeline)
+	cp.Pipeline.Name = "test"
+	cp.Pipeline.Type = gaia.PTypePerl
+	gaia.Cfg.SourceRoot = tmp
+	cp.SourcePath = "src"
+	cp.Artifacts = []string{"templates/*.tmpl"}
+	CreatePipeline(cp)
+	if cp.StatusType != gaia.CreatePipelineSuccess {
+		t.Fatalf("pipeline status was not success. was: %s output: %s", cp.StatusType, cp.Output)
+	}
+
+	// The build moved the local destination, artifacts come from the sources
+	binary := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(cp.Pipeline.Name, cp.Pipeline.Type))
+	content, err := ioutil.ReadFile(filepath.Join(binary+artifactsSuffix, "templates", "index.tmpl"))
+	if err != nil || string(content) != "index" {
+		t.Fatalf("expected artifact next to the archive. error: %v", err)
+	}
+}
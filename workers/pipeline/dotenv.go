@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+var (
+	// dotEnvKeyRegex defines which variable names are allowed in env files.
+	dotEnvKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+	// errEnvFileOutsideRepo is thrown when the env file path points outside
+	// of the cloned repository.
+	errEnvFileOutsideRepo = errors.New("env file must be located inside the repository")
+)
+
+// buildEnvironment returns the environment used for all build commands.
+// It is the environment of the current process with the configured build
+// proxy merged with the variables from the env file of the pipeline, if one
+// has been defined.
+// Values from the env file are never logged because they can contain secrets.
+func buildEnvironment(p *gaia.CreatePipeline) ([]string, error) {
+	env := proxyEnvironment(os.Environ())
+	if p.EnvFile == "" {
+		return env, nil
+	}
+
+	// The env file is relative to the repository root and must not escape it
+	localDest := ""
+	if p.Pipeline.Repo != nil {
+		localDest = p.Pipeline.Repo.LocalDest
+	}
+	envFile := filepath.Join(localDest, filepath.Clean(p.EnvFile))
+	rel, err := filepath.Rel(localDest, envFile)
+	if err != nil || filepath.IsAbs(p.EnvFile) || strings.HasPrefix(rel, "..") {
+		return nil, errEnvFileOutsideRepo
+	}
+
+	f, err := os.Open(envFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot find env file %s in repository", p.EnvFile)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	vars, err := parseDotEnv(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse env file %s: %s", p.EnvFile, err.Error())
+	}
+	return append(env, vars...), nil
+}
+
+// parseDotEnv parses the content of an env file in the common dotenv format
+// and returns all variables in the form of "key=value".
+// Empty lines and comments are ignored. Keys can be prefixed with "export".
+// Double quoted values support escape sequences, single quoted values are
+// taken literally and unquoted values end at an inline comment.
+func parseDotEnv(r io.Reader) ([]string, error) {
+	vars := []string{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		// Split key and value
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("missing '=' in line %d", lineNumber)
+		}
+		key := strings.TrimSpace(line[:i])
+		if !dotEnvKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable name in line %d", lineNumber)
+		}
+
+		value, err := parseDotEnvValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s in line %d", err.Error(), lineNumber)
+		}
+		vars = append(vars, key+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// parseDotEnvValue parses a single value of an env file.
+func parseDotEnvValue(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+
+	switch v[0] {
+	case '\'':
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", errors.New("missing closing quote")
+		}
+		if !isDotEnvComment(v[end+2:]) {
+			return "", errors.New("unexpected characters after closing quote")
+		}
+		return v[1 : end+1], nil
+	case '"':
+		var value strings.Builder
+		for i := 1; i < len(v); i++ {
+			switch c := v[i]; {
+			case c == '\\' && i+1 < len(v):
+				i++
+				switch v[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 't':
+					value.WriteByte('\t')
+				case 'r':
+					value.WriteByte('\r')
+				default:
+					value.WriteByte(v[i])
+				}
+			case c == '"':
+				if !isDotEnvComment(v[i+1:]) {
+					return "", errors.New("unexpected characters after closing quote")
+				}
+				return value.String(), nil
+			default:
+				value.WriteByte(c)
+			}
+		}
+		return "", errors.New("missing closing quote")
+	}
+
+	// Unquoted values end at an inline comment
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// isDotEnvComment checks if the given rest of a line is empty or a comment.
+func isDotEnvComment(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
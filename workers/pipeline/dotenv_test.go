@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	content := `# build configuration
+GOFLAGS=-mod=vendor
+export CGO_ENABLED=0
+EMPTY=
+SPACED = value with spaces # inline comment
+DOUBLE="line1\nline2 # not a comment"
+SINGLE='raw \n value' # comment
+HASH=abc#def
+`
+	vars, err := parseDotEnv(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"GOFLAGS=-mod=vendor",
+		"CGO_ENABLED=0",
+		"EMPTY=",
+		"SPACED=value with spaces",
+		"DOUBLE=line1\nline2 # not a comment",
+		`SINGLE=raw \n value`,
+		"HASH=abc#def",
+	}
+	if len(vars) != len(expected) {
+		t.Fatalf("expected %d variables but got %d: %v", len(expected), len(vars), vars)
+	}
+	for i := range expected {
+		if vars[i] != expected[i] {
+			t.Fatalf("expected '%s' but got '%s'", expected[i], vars[i])
+		}
+	}
+}
+
+func TestParseDotEnvInvalid(t *testing.T) {
+	tests := []string{
+		"NOVALUE",
+		"1INVALID=value",
+		`UNCLOSED="value`,
+		`UNCLOSED='value`,
+		`TRAILING="value" rest`,
+	}
+	for _, content := range tests {
+		if _, err := parseDotEnv(strings.NewReader(content)); err == nil {
+			t.Fatalf("expected error for '%s'", content)
+		}
+	}
+}
+
+func TestBuildEnvironmentWithEnvFile(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestBuildEnvironmentWithEnvFile")
+	defer os.RemoveAll(tmp)
+	_ = ioutil.WriteFile(filepath.Join(tmp, ".env"), []byte("GAIA_TEST_VAR=\"secret value\"\n"), 0600)
+
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	p.EnvFile = ".env"
+	env, err := buildEnvironment(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env[len(env)-1] != "GAIA_TEST_VAR=secret value" {
+		t.Fatalf("expected env file variable at the end of the environment. got: %s", env[len(env)-1])
+	}
+
+	// Missing env file
+	p.EnvFile = "missing.env"
+	_, err = buildEnvironment(p)
+	if err == nil || !strings.Contains(err.Error(), "missing.env") {
+		t.Fatalf("expected error naming the missing env file. got: %v", err)
+	}
+
+	// Env file outside of the repository
+	p.EnvFile = "../.env"
+	if _, err = buildEnvironment(p); err != errEnvFileOutsideRepo {
+		t.Fatalf("expected error '%v' but got '%v'", errEnvFileOutsideRepo, err)
+	}
+}
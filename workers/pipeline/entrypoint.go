@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// entrypointTypes are the pipeline types which are started from a script
+// in the sources and support a custom entrypoint.
+var entrypointTypes = map[gaia.PipelineType]bool{
+	gaia.PTypeNodeJS: true,
+	gaia.PTypePerl:   true,
+	gaia.PTypeR:      true,
+}
+
+// validateEntrypoint checks the entrypoint of the given pipeline. It must
+// be a file inside of the source tree of a supported pipeline type.
+func validateEntrypoint(p *gaia.CreatePipeline) error {
+	if p.Entrypoint == "" {
+		return nil
+	}
+	if !entrypointTypes[p.Pipeline.Type] {
+		return fmt.Errorf("entrypoint is not supported for %s pipelines", p.Pipeline.Type)
+	}
+	clean := path.Clean(p.Entrypoint)
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("entrypoint %s must be a file relative to the source root", p.Entrypoint)
+	}
+	p.Entrypoint = clean
+	return nil
+}
+
+// checkEntrypoint checks if the given entrypoint exists in the sources in
+// the given folder. An empty entrypoint selects the default of the type.
+func checkEntrypoint(root, entrypoint string) error {
+	if entrypoint == "" {
+		return nil
+	}
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(entrypoint)))
+	if err != nil || info.IsDir() {
+		return fmt.Errorf("cannot find entrypoint %s in repository", entrypoint)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestValidateEntrypoint(t *testing.T) {
+	p := &gaia.CreatePipeline{Entrypoint: "main.py"}
+	p.Pipeline.Type = gaia.PTypePython
+	if err := validateEntrypoint(p); err == nil || !strings.Contains(err.Error(), "not supported for python") {
+		t.Fatalf("expected unsupported type error but got %v", err)
+	}
+
+	p.Pipeline.Type = gaia.PTypeNodeJS
+	for _, entrypoint := range []string{"/index.js", "../index.js", "src/../../index.js", "."} {
+		p.Entrypoint = entrypoint
+		if err := validateEntrypoint(p); err == nil {
+			t.Fatalf("expected entrypoint %s to be rejected", entrypoint)
+		}
+	}
+	p.Entrypoint = "./src//start.js"
+	if err := validateEntrypoint(p); err != nil || p.Entrypoint != "src/start.js" {
+		t.Fatalf("expected cleaned entrypoint but got %s: %v", p.Entrypoint, err)
+	}
+}
+
+func TestExecuteBuildMissingEntrypoint(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestExecuteBuildMissingEntrypoint")
+	defer os.RemoveAll(tmp)
+	defer fakeToolchain(t, tmp, tarName)()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	b := new(BuildPipelineNodeJS)
+	p := &gaia.CreatePipeline{Entrypoint: "src/start.js"}
+	p.Pipeline.Type = gaia.PTypeNodeJS
+	if err := b.PrepareEnvironment(p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Pipeline.Entrypoint != "src/start.js" {
+		t.Fatalf("expected entrypoint to be recorded but got %q", p.Pipeline.Entrypoint)
+	}
+
+	err := b.ExecuteBuild(p)
+	if err == nil || !strings.Contains(err.Error(), "cannot find entrypoint src/start.js") {
+		t.Fatalf("expected missing entrypoint error but got %v", err)
+	}
+	var compile *ErrCompileFailed
+	if !errors.As(err, &compile) {
+		t.Fatalf("expected compile error but got %T", err)
+	}
+
+	_ = os.MkdirAll(filepath.Join(p.Pipeline.Repo.LocalDest, "src"), 0700)
+	_ = ioutil.WriteFile(filepath.Join(p.Pipeline.Repo.LocalDest, "src", "start.js"), nil, 0600)
+	if err := checkEntrypoint(p.Pipeline.Repo.LocalDest, p.Pipeline.Entrypoint); err != nil {
+		t.Fatal(err)
+	}
+}
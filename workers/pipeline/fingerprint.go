@@ -0,0 +1,416 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/filehelper"
+)
+
+const (
+	// Keys of the build flags stored in the fingerprint
+	flagTarget     = "target"
+	flagEnvFile    = "envfile"
+	flagSourcePath = "sourcepath"
+	flagImage      = "image"
+	flagLint       = "lint"
+	flagVCS        = "vcs"
+	flagGoPlugin   = "goplugin"
+	flagTinyGo     = "tinygo"
+	flagArtifacts  = "artifacts"
+	flagEntrypoint = "entrypoint"
+
+	// flagReplacePrefix prefixes the flags of replaced modules
+	flagReplacePrefix = "replace:"
+)
+
+var (
+	// toolchainBinaries are the binaries which build the pipeline types.
+	toolchainBinaries = map[gaia.PipelineType][]string{
+		gaia.PTypeGolang:  {golangBinaryName},
+		gaia.PTypeJava:    {mavenBinaryName},
+		gaia.PTypePython:  {pythonBinaryName},
+		gaia.PTypeCpp:     {cppBinaryName},
+		gaia.PTypeRuby:    {gemBinaryName},
+		gaia.PTypeNodeJS:  {tarName},
+		gaia.PTypePerl:    {perlBinaryName, cpanmBinaryName, tarName},
+		gaia.PTypeR:       {rscriptBinaryName, tarName},
+		gaia.PTypeHaskell: {ghcBinaryName},
+	}
+
+	// secretEnvMarkers mark environment variables which are not part
+	// of the fingerprint because they probably contain secrets.
+	secretEnvMarkers = []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "KEY", "CREDENTIAL"}
+
+	// errNoFingerprint is thrown when a pipeline has no recorded fingerprint.
+	errNoFingerprint = errors.New("no build fingerprint recorded for pipeline")
+
+	// errImageNotVerifiable is thrown when the reproducibility of an image is verified.
+	errImageNotVerifiable = errors.New("reproducibility of container images cannot be verified")
+)
+
+// hashSourceTree hashes the paths, modes and contents of all files in the
+// given folder. The git folder is skipped because it contains clone specific data.
+func hashSourceTree(root string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00", filepath.ToSlash(rel), info.Mode())
+
+		switch mode := info.Mode(); {
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_, _ = io.WriteString(h, link)
+		case mode.IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+		_, _ = h.Write([]byte{0})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashEnvironment hashes the sorted build environment.
+// Variables which look like secrets are skipped.
+func hashEnvironment(env []string) string {
+	vars := make([]string, 0, len(env))
+	for _, v := range env {
+		key := strings.ToUpper(strings.SplitN(v, "=", 2)[0])
+		secret := false
+		for _, marker := range secretEnvMarkers {
+			if strings.Contains(key, marker) {
+				secret = true
+				break
+			}
+		}
+		if !secret {
+			vars = append(vars, v)
+		}
+	}
+	sort.Strings(vars)
+
+	h := sha256.New()
+	for _, v := range vars {
+		_, _ = io.WriteString(h, v+"\x00")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// toolchainFingerprint identifies the toolchain binaries of the given
+// pipeline type by their path and checksum.
+func toolchainFingerprint(pType gaia.PipelineType) (string, error) {
+	var parts []string
+	for _, binary := range toolchainBinaries[pType] {
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return "", err
+		}
+		checksum, err := filehelper.GetSHA256Sum(path)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s@sha256:%x", path, checksum))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// buildFlags returns the build options of the given pipeline.
+func buildFlags(p *gaia.CreatePipeline) map[string]string {
+	flags := map[string]string{}
+	if p.Target != nil {
+		flags[flagTarget] = p.Target.OS + "/" + p.Target.Arch
+	}
+	if p.EnvFile != "" {
+		flags[flagEnvFile] = p.EnvFile
+	}
+	if p.SourcePath != "" {
+		flags[flagSourcePath] = p.SourcePath
+	}
+	if p.Image != nil {
+		flags[flagImage] = p.Image.Dockerfile
+	}
+	if p.Lint {
+		flags[flagLint] = "true"
+	}
+	if p.VCS != "" {
+		flags[flagVCS] = string(p.VCS)
+	}
+	if p.GoPlugin {
+		flags[flagGoPlugin] = "true"
+	}
+	if p.TinyGo {
+		flags[flagTinyGo] = p.TinyGoTarget
+	}
+	if len(p.Artifacts) > 0 {
+		flags[flagArtifacts] = strings.Join(p.Artifacts, ",")
+	}
+	if p.Entrypoint != "" {
+		flags[flagEntrypoint] = p.Entrypoint
+	}
+	for module, path := range p.Replace {
+		flags[flagReplacePrefix+module] = path
+	}
+	return flags
+}
+
+// buildSpec hashes all options of the given build request. The status of
+// the build, the results of the build, the build folder and the credentials
+// are not part of it.
+func buildSpec(p *gaia.CreatePipeline) (string, error) {
+	spec := *p
+	spec.ID = ""
+	spec.Status = 0
+	spec.StatusType = ""
+	spec.Output = ""
+	spec.Created = time.Time{}
+	spec.GitHubToken = ""
+	spec.IdempotencyKey = ""
+	spec.MatrixResults = nil
+
+	spec.Pipeline.ID = 0
+	spec.Pipeline.ExecPath = ""
+	spec.Pipeline.SHA256Sum = nil
+	spec.Pipeline.Jobs = nil
+	spec.Pipeline.Created = time.Time{}
+	spec.Pipeline.UUID = ""
+	spec.Pipeline.IsNotValid = false
+	spec.Pipeline.TriggerToken = ""
+	spec.Pipeline.ImageRef = ""
+	spec.Pipeline.Fingerprint = nil
+	spec.Pipeline.PluginModule = false
+	spec.Pipeline.Metadata = nil
+	spec.Pipeline.BuildOptions = nil
+	if p.Pipeline.Repo != nil {
+		repo := *p.Pipeline.Repo
+		repo.Username = ""
+		repo.Password = ""
+		repo.PrivateKey = gaia.PrivateKey{}
+		repo.Branches = nil
+		repo.Revision = ""
+		repo.LocalDest = ""
+		spec.Pipeline.Repo = &repo
+	}
+
+	content, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// fingerprintInputs computes the fingerprint of the build inputs.
+func fingerprintInputs(p *gaia.CreatePipeline, sourceHash string) (*gaia.BuildFingerprint, error) {
+	env, err := buildEnvironment(p)
+	if err != nil {
+		return nil, err
+	}
+	toolchain, err := toolchainFingerprint(p.Pipeline.Type)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := buildSpec(p)
+	if err != nil {
+		return nil, err
+	}
+	return &gaia.BuildFingerprint{
+		SourceHash: sourceHash,
+		Toolchain:  toolchain,
+		EnvHash:    hashEnvironment(env),
+		Flags:      buildFlags(p),
+		SpecHash:   spec,
+	}, nil
+}
+
+// fingerprintBuild computes the fingerprint of a finished build.
+// The source hash must be computed before the build because the build
+// writes its results into the source folder.
+func fingerprintBuild(p *gaia.CreatePipeline, sourceHash string) (*gaia.BuildFingerprint, error) {
+	f, err := fingerprintInputs(p, sourceHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Images are pushed to a registry and have no local output
+	if p.Pipeline.ExecPath != "" {
+		checksum, err := filehelper.GetSHA256Sum(p.Pipeline.ExecPath)
+		if err != nil {
+			return nil, err
+		}
+		f.OutputHash = hex.EncodeToString(checksum)
+	}
+	return f, nil
+}
+
+// upToDate checks if the inputs and the options of the given build equal
+// the ones of the last build of the pipeline and the installed binary is
+// still the output of the last build. The build can be skipped in that case.
+func upToDate(p *gaia.CreatePipeline, sourceHash string) bool {
+	// Images are not stored locally
+	if p.Image != nil {
+		return false
+	}
+	active := GetGlobalActivePipelines().GetByName(p.Pipeline.Name)
+	if active == nil || active.Type != p.Pipeline.Type || active.Fingerprint == nil || active.Fingerprint.OutputHash == "" {
+		return false
+	}
+
+	f, err := fingerprintInputs(p, sourceHash)
+	if err != nil {
+		return false
+	}
+	f.OutputHash = active.Fingerprint.OutputHash
+	if len(diffFingerprints(active.Fingerprint, f)) != 0 || f.SpecHash != active.Fingerprint.SpecHash {
+		return false
+	}
+	checksum, err := filehelper.GetSHA256Sum(binaryDestination(p))
+	return err == nil && hex.EncodeToString(checksum) == active.Fingerprint.OutputHash
+}
+
+// diffFingerprints returns the names of all fields which differ.
+func diffFingerprints(a, b *gaia.BuildFingerprint) []string {
+	var diff []string
+	if a.SourceHash != b.SourceHash {
+		diff = append(diff, "source")
+	}
+	if a.Toolchain != b.Toolchain {
+		diff = append(diff, "toolchain")
+	}
+	if a.EnvHash != b.EnvHash {
+		diff = append(diff, "env")
+	}
+	if len(a.Flags) != len(b.Flags) {
+		diff = append(diff, "flags")
+	} else {
+		for k, v := range a.Flags {
+			if b.Flags[k] != v {
+				diff = append(diff, "flags")
+				break
+			}
+		}
+	}
+	if a.OutputHash != b.OutputHash {
+		diff = append(diff, "output")
+	}
+	return diff
+}
+
+// VerifyReproducible rebuilds the pipeline with the given name from the
+// same inputs and compares the fingerprint with the recorded one.
+// It returns the names of the differing fingerprint fields. The build
+// result is discarded. An empty result means the build is reproducible.
+func VerifyReproducible(name string) ([]string, error) {
+	active := GetGlobalActivePipelines().GetByName(name)
+	if active == nil {
+		return nil, fmt.Errorf("cannot find pipeline %s", name)
+	}
+	if active.Fingerprint == nil {
+		return nil, errNoFingerprint
+	}
+	if _, ok := active.Fingerprint.Flags[flagImage]; ok {
+		return nil, errImageNotVerifiable
+	}
+
+	bP := newBuildPipeline(active.Type)
+	if bP == nil {
+		return nil, fmt.Errorf("pipeline type %s is not supported", active.Type)
+	}
+
+	// Rebuild from the recorded revision and build options
+	p := &gaia.CreatePipeline{}
+	p.Pipeline.Name = active.Name
+	p.Pipeline.Type = active.Type
+	if active.Repo != nil {
+		repo := *active.Repo
+		repo.PinnedRevision = repo.Revision
+		p.Pipeline.Repo = &repo
+	}
+	flags := active.Fingerprint.Flags
+	if target, ok := flags[flagTarget]; ok {
+		t := strings.SplitN(target, "/", 2)
+		p.Target = &gaia.BuildTarget{OS: t[0]}
+		if len(t) > 1 {
+			p.Target.Arch = t[1]
+		}
+	}
+	p.EnvFile = flags[flagEnvFile]
+	p.SourcePath = flags[flagSourcePath]
+	_, p.Lint = flags[flagLint]
+	_, p.GoPlugin = flags[flagGoPlugin]
+	p.TinyGoTarget, p.TinyGo = flags[flagTinyGo]
+	p.VCS = gaia.VCSType(flags[flagVCS])
+	p.Entrypoint = flags[flagEntrypoint]
+	if artifacts, ok := flags[flagArtifacts]; ok {
+		p.Artifacts = strings.Split(artifacts, ",")
+	}
+	for flag, path := range flags {
+		if strings.HasPrefix(flag, flagReplacePrefix) {
+			if p.Replace == nil {
+				p.Replace = map[string]string{}
+			}
+			p.Replace[strings.TrimPrefix(flag, flagReplacePrefix)] = path
+		}
+	}
+
+	release := acquireBuildLock(p.Pipeline.Name)
+	defer release()
+	defer cleanupBuildFolder(p)
+
+	if err := bP.PrepareEnvironment(p); err != nil {
+		return nil, fmt.Errorf("cannot prepare build: %s", err.Error())
+	}
+	if err := acquireSource(p); err != nil {
+		return nil, fmt.Errorf("cannot prepare build: %s", err.Error())
+	}
+	if err := transformSource(p); err != nil {
+		return nil, fmt.Errorf("cannot transform sources: %s", err.Error())
+	}
+	sourceHash, err := hashSourceTree(p.Pipeline.Repo.LocalDest)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash sources: %s", err.Error())
+	}
+	if err := bP.ExecuteBuild(p); err != nil {
+		return nil, fmt.Errorf("cannot build pipeline: %s", err.Error())
+	}
+	f, err := fingerprintBuild(p, sourceHash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fingerprint build: %s", err.Error())
+	}
+	return diffFingerprints(active.Fingerprint, f), nil
+}
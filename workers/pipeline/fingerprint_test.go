@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+	uuid "github.com/satori/go.uuid"
+)
+
+// fingerprintBuildPipeline copies the sources into the build result.
+// If random is set, every build result is unique.
+type fingerprintBuildPipeline struct {
+	BuildPipelineGolang
+	random bool
+}
+
+func (b *fingerprintBuildPipeline) PrepareEnvironment(p *gaia.CreatePipeline) error {
+	p.Pipeline.UUID = uuid.Must(uuid.NewV4(), nil).String()
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, srcFolder, p.Pipeline.UUID)}
+	return os.MkdirAll(p.Pipeline.Repo.LocalDest, 0700)
+}
+
+func (b *fingerprintBuildPipeline) ExecuteBuild(p *gaia.CreatePipeline) error {
+	content, err := ioutil.ReadFile(filepath.Join(p.Pipeline.Repo.LocalDest, "main.txt"))
+	if err != nil {
+		return err
+	}
+	if b.random {
+		content = append(content, []byte(p.Pipeline.UUID)...)
+	}
+	p.Pipeline.ExecPath = filepath.Join(p.Pipeline.Repo.LocalDest, "out")
+	return ioutil.WriteFile(p.Pipeline.ExecPath, content, 0700)
+}
+
+func TestHashSourceTree(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestHashSourceTree")
+	defer os.RemoveAll(tmp)
+	_ = os.MkdirAll(filepath.Join(tmp, ".git"), 0700)
+	_ = ioutil.WriteFile(filepath.Join(tmp, "main.go"), []byte("package main"), 0600)
+
+	hash, err := hashSourceTree(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The git folder is not part of the sources
+	_ = ioutil.WriteFile(filepath.Join(tmp, ".git", "HEAD"), []byte("ref"), 0600)
+	if h, _ := hashSourceTree(tmp); h != hash {
+		t.Fatal("changes in the git folder should not change the source hash")
+	}
+
+	_ = ioutil.WriteFile(filepath.Join(tmp, "main.go"), []byte("package other"), 0600)
+	if h, _ := hashSourceTree(tmp); h == hash {
+		t.Fatal("changed sources should change the source hash")
+	}
+}
+
+func TestHashEnvironment(t *testing.T) {
+	hash := hashEnvironment([]string{"PATH=/bin", "GOOS=linux"})
+	if h := hashEnvironment([]string{"GOOS=linux", "PATH=/bin"}); h != hash {
+		t.Fatal("the order of the environment should not change the hash")
+	}
+	if h := hashEnvironment([]string{"GOOS=linux", "PATH=/bin", "GITHUB_TOKEN=abc", "db_password=abc"}); h != hash {
+		t.Fatal("secrets should not be part of the environment hash")
+	}
+	if h := hashEnvironment([]string{"GOOS=windows", "PATH=/bin"}); h == hash {
+		t.Fatal("changed environment should change the hash")
+	}
+}
+
+func TestDiffFingerprints(t *testing.T) {
+	a := &gaia.BuildFingerprint{SourceHash: "a", Toolchain: "go", EnvHash: "e", Flags: map[string]string{flagTarget: "linux/amd64"}, OutputHash: "o"}
+	b := *a
+	if diff := diffFingerprints(a, &b); len(diff) != 0 {
+		t.Fatalf("expected equal fingerprints but got %v", diff)
+	}
+	b.Flags = map[string]string{flagTarget: "linux/arm64"}
+	b.OutputHash = "x"
+	diff := diffFingerprints(a, &b)
+	if fmt.Sprint(diff) != "[flags output]" {
+		t.Fatalf("expected flags and output to differ but got %v", diff)
+	}
+}
+
+func TestVerifyReproducible(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestVerifyReproducible")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	mockType := gaia.PipelineType("fingerprint")
+	bP := &fingerprintBuildPipeline{}
+	RegisterBuildPipeline(mockType, func() BuildPipeline { return bP })
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+
+	src := filepath.Join(tmp, "src")
+	_ = os.Mkdir(src, 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, "main.txt"), []byte("content"), 0600)
+
+	// Build the pipeline once to record the fingerprint
+	gaia.Cfg.SourceRoot = tmp
+	p := &gaia.CreatePipeline{SourcePath: src}
+	p.Pipeline.Name = "TestVerifyReproducible"
+	p.Pipeline.Type = mockType
+	if err := bP.PrepareEnvironment(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := acquireSource(p); err != nil {
+		t.Fatal(err)
+	}
+	sourceHash, err := hashSourceTree(p.Pipeline.Repo.LocalDest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bP.ExecuteBuild(p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Pipeline.Fingerprint, err = fingerprintBuild(p, sourceHash); err != nil {
+		t.Fatal(err)
+	}
+
+	SetGlobalActivePipelines(NewActivePipelines())
+	if _, err := VerifyReproducible(p.Pipeline.Name); err == nil {
+		t.Fatal("expected error for unknown pipeline")
+	}
+	GetGlobalActivePipelines().Append(p.Pipeline)
+
+	diff, err := VerifyReproducible(p.Pipeline.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 0 {
+		t.Fatalf("expected reproducible build but got differences in %v", diff)
+	}
+
+	// Unique build results are detected
+	bP.random = true
+	diff, err = VerifyReproducible(p.Pipeline.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(diff) != "[output]" {
+		t.Fatalf("expected different output but got %v", diff)
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestUpToDate")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	p := &gaia.CreatePipeline{}
+	p.Pipeline.Name = "TestUpToDate"
+	p.Pipeline.Type = gaia.PipelineType("fingerprint")
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	p.Pipeline.ExecPath = filepath.Join(tmp, "out")
+	_ = ioutil.WriteFile(p.Pipeline.ExecPath, []byte("binary"), 0700)
+	if err := installBinary(p.Pipeline.ExecPath, binaryDestination(p)); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if p.Pipeline.Fingerprint, err = fingerprintBuild(p, "source"); err != nil {
+		t.Fatal(err)
+	}
+
+	SetGlobalActivePipelines(NewActivePipelines())
+	if upToDate(p, "source") {
+		t.Fatal("unknown pipeline should not be up to date")
+	}
+	GetGlobalActivePipelines().Append(p.Pipeline)
+	if !upToDate(p, "source") {
+		t.Fatal("expected pipeline to be up to date")
+	}
+	if upToDate(p, "changed") {
+		t.Fatal("pipeline with changed sources should not be up to date")
+	}
+
+	// The status of the request is not an option of the build
+	p.ID = "retry"
+	p.Output = "output"
+	if !upToDate(p, "source") {
+		t.Fatal("expected pipeline with other status to be up to date")
+	}
+
+	// All options which affect the build or the stored pipeline are checked
+	changes := []func(p *gaia.CreatePipeline){
+		func(p *gaia.CreatePipeline) { p.Matrix = []gaia.BuildTarget{{OS: "linux", Arch: "arm64"}} },
+		func(p *gaia.CreatePipeline) { p.PreserveSource = true },
+		func(p *gaia.CreatePipeline) { p.SmokeTestFlag = "--version" },
+		func(p *gaia.CreatePipeline) { p.SmokeTestTimeout = 5 },
+		func(p *gaia.CreatePipeline) { p.Pipeline.PathFilters = []string{"api"} },
+		func(p *gaia.CreatePipeline) { p.Pipeline.DefaultArgs = []*gaia.Argument{{Key: "env", Value: "prod"}} },
+		func(p *gaia.CreatePipeline) { p.Pipeline.NotificationPolicy = gaia.NotifyAlways },
+	}
+	for i, change := range changes {
+		changed := *p
+		change(&changed)
+		if upToDate(&changed, "source") {
+			t.Fatalf("pipeline with changed option %d should not be up to date", i)
+		}
+	}
+
+	// A modified binary must be rebuilt
+	_ = ioutil.WriteFile(binaryDestination(p), []byte("modified"), 0700)
+	if upToDate(p, "source") {
+		t.Fatal("pipeline with modified binary should not be up to date")
+	}
+}
@@ -5,13 +5,17 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	gohttp "net/http"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/google/go-github/github"
 	gossh "golang.org/x/crypto/ssh"
@@ -111,11 +115,18 @@ func UpdateRepository(pipe *gaia.Pipeline) error {
 		return err
 	}
 	tree, _ := r.Worktree()
+
+	// Remember the revision before the pull for the path filters
+	var from plumbing.Hash
+	if head, err := r.Head(); err == nil {
+		from = head.Hash()
+	}
 	o := &git.PullOptions{
-		ReferenceName: plumbing.ReferenceName(pipe.Repo.SelectedBranch),
-		SingleBranch:  true,
-		RemoteName:    "origin",
-		Auth:          auth,
+		ReferenceName:     plumbing.ReferenceName(pipe.Repo.SelectedBranch),
+		SingleBranch:      true,
+		RemoteName:        "origin",
+		Auth:              auth,
+		RecurseSubmodules: submoduleRecursion(pipe.Repo),
 	}
 	err = tree.Pull(o)
 	if err != nil {
@@ -137,20 +148,37 @@ func UpdateRepository(pipe *gaia.Pipeline) error {
 		}
 	}
 
+	// Rebuild the pipeline with the options of its last build
+	createPipeline := updateRequest(pipe)
+	if updateUnchanged(createPipeline, r, from) {
+		gaia.Cfg.Logger.Debug("no changes match the path filters, skipping update: ", "message", pipe.Name)
+		return nil
+	}
+
 	gaia.Cfg.Logger.Debug("updating pipeline: ", "message", pipe.Name)
-	b := newBuildPipeline(pipe.Type)
-	createPipeline := &gaia.CreatePipeline{
-		Pipeline: gaia.Pipeline{
-			Repo: &gaia.GitRepo{},
-		},
-	}
-	createPipeline.Pipeline = *pipe
-	_ = b.ExecuteBuild(createPipeline)
-	_ = b.CopyBinary(createPipeline)
+	CreatePipeline(createPipeline)
+	if createPipeline.StatusType == gaia.CreatePipelineFailed {
+		gaia.Cfg.Logger.Error("cannot update pipeline: ", "pipeline", pipe.Name, "output", createPipeline.Output)
+		return fmt.Errorf("cannot update pipeline %s: %s", pipe.Name, createPipeline.Output)
+	}
 	gaia.Cfg.Logger.Debug("successfully updated: ", "message", pipe.Name)
 	return nil
 }
 
+// updateRequest returns the request which rebuilds the given pipeline
+// with the options of its last build.
+func updateRequest(pipe *gaia.Pipeline) *gaia.CreatePipeline {
+	p := &gaia.CreatePipeline{}
+	p.ID = security.GenerateRandomUUIDV5()
+	p.Created = time.Now()
+	p.Pipeline = *pipe
+	repo := *pipe.Repo
+	p.Pipeline.Repo = &repo
+	p.PathFilters = pipe.PathFilters
+	applyBuildOptions(p, pipe.BuildOptions)
+	return p
+}
+
 // gitCloneRepo clones the given repo to a local folder.
 // The destination will be attached to the given repo obj.
 func gitCloneRepo(repo *gaia.GitRepo) error {
@@ -162,12 +190,12 @@ func gitCloneRepo(repo *gaia.GitRepo) error {
 	o := &git.CloneOptions{
 		Auth:              auth,
 		URL:               repo.URL,
-		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		RecurseSubmodules: submoduleRecursion(repo),
 		SingleBranch:      true,
 		ReferenceName:     plumbing.ReferenceName(repo.SelectedBranch),
 	}
 	// Clone repo
-	_, err = git.PlainClone(repo.LocalDest, false, o)
+	r, err := git.PlainClone(repo.LocalDest, false, o)
 	if err != nil {
 		if strings.Contains(err.Error(), "knownhosts: key is unknown") {
 			gaia.Cfg.Logger.Warn("Warning: Unknown host key.", "error", err.Error(), "URL", repo.URL)
@@ -177,7 +205,7 @@ func gitCloneRepo(repo *gaia.GitRepo) error {
 			}
 			o.Auth = auth
 			// Clone repo again with no host key verification.
-			_, err = git.PlainClone(repo.LocalDest, false, o)
+			r, err = git.PlainClone(repo.LocalDest, false, o)
 			if err != nil {
 				return err
 			}
@@ -186,12 +214,85 @@ func gitCloneRepo(repo *gaia.GitRepo) error {
 		}
 	}
 
+	// Check out the pinned revision instead of the branch head
+	if repo.PinnedRevision != "" {
+		tree, err := r.Worktree()
+		if err != nil {
+			return err
+		}
+		hash, err := r.ResolveRevision(plumbing.Revision(repo.PinnedRevision))
+		if err != nil {
+			return fmt.Errorf("cannot find pinned revision %s: %s", repo.PinnedRevision, err.Error())
+		}
+		if err = tree.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+			return err
+		}
+
+		// The clone initialized the submodules at the branch head
+		if !repo.SkipSubmodules {
+			subs, err := tree.Submodules()
+			if err != nil {
+				return err
+			}
+			err = subs.Update(&git.SubmoduleUpdateOptions{
+				Init:              true,
+				RecurseSubmodules: submoduleRecursion(repo),
+				Auth:              o.Auth,
+			})
+			if err != nil {
+				return fmt.Errorf("cannot update submodules to pinned revision %s: %s", repo.PinnedRevision, err.Error())
+			}
+		}
+	}
+
+	// Remember the cloned revision
+	ref, err := r.Head()
+	if err != nil {
+		return err
+	}
+	repo.Revision = ref.Hash().String()
 	return nil
 }
 
+// checkCleanWorktree returns an error which lists all changed files
+// if the git working tree in the given folder is dirty.
+func checkCleanWorktree(dir string) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	tree, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := tree.Status()
+	if err != nil {
+		return err
+	}
+	if status.IsClean() {
+		return nil
+	}
+
+	files := make([]string, 0, len(status))
+	for file := range status {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return fmt.Errorf("git working tree is dirty: %s", strings.Join(files, ", "))
+}
+
+// submoduleRecursion returns how deep the submodules of the given repo
+// are initialized. The auth of the repository is used for the submodules.
+func submoduleRecursion(repo *gaia.GitRepo) git.SubmoduleRescursivity {
+	if repo.SkipSubmodules {
+		return git.NoRecurseSubmodules
+	}
+	return git.DefaultSubmoduleRecursionDepth
+}
+
 func updateAllCurrentPipelines() {
 	gaia.Cfg.Logger.Debug("starting updating of pipelines...")
-	allPipelines := GlobalActivePipelines.GetAll()
+	allPipelines := GetGlobalActivePipelines().GetAll()
 	var wg sync.WaitGroup
 	sem := make(chan int, 4)
 	for _, p := range allPipelines {
@@ -7,14 +7,18 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/google/go-github/github"
 	"github.com/hashicorp/go-hclog"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func TestGitCloneRepo(t *testing.T) {
@@ -29,6 +33,102 @@ func TestGitCloneRepo(t *testing.T) {
 	}
 }
 
+func TestSubmoduleRecursion(t *testing.T) {
+	repo := &gaia.GitRepo{}
+	if r := submoduleRecursion(repo); r != git.DefaultSubmoduleRecursionDepth {
+		t.Fatalf("expected submodules to be initialized but got recursion depth %d", r)
+	}
+	repo.SkipSubmodules = true
+	if r := submoduleRecursion(repo); r != git.NoRecurseSubmodules {
+		t.Fatalf("expected submodules to be skipped but got recursion depth %d", r)
+	}
+}
+
+func TestGitCloneRepoPinnedRevisionSubmodules(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp, _ := ioutil.TempDir("", "TestGitCloneRepoPinnedRevisionSubmodules")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	run := func(dir string, args ...string) string {
+		args = append([]string{"-c", "protocol.file.allow=always", "-c", "user.name=gaia", "-c", "user.email=gaia@example.com"}, args...)
+		cmd := exec.Command(gitPath, args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s failed: %s", strings.Join(args, " "), string(out))
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	// The submodule is updated after the pinned revision of the parent
+	sub := filepath.Join(tmp, "sub")
+	parent := filepath.Join(tmp, "parent")
+	_ = os.MkdirAll(sub, 0700)
+	_ = os.MkdirAll(parent, 0700)
+	run(sub, "init", "-q")
+	_ = ioutil.WriteFile(filepath.Join(sub, "version"), []byte("1"), 0600)
+	run(sub, "add", "version")
+	run(sub, "commit", "-q", "-m", "version 1")
+	run(parent, "init", "-q")
+	run(parent, "submodule", "add", "-q", sub, "lib")
+	run(parent, "commit", "-q", "-m", "add submodule")
+	pinned := run(parent, "rev-parse", "HEAD")
+	_ = ioutil.WriteFile(filepath.Join(sub, "version"), []byte("2"), 0600)
+	run(sub, "commit", "-q", "-a", "-m", "version 2")
+	run(filepath.Join(parent, "lib"), "pull", "-q", "origin", "HEAD")
+	run(parent, "commit", "-q", "-a", "-m", "update submodule")
+
+	repo := &gaia.GitRepo{
+		URL:            parent,
+		PinnedRevision: pinned,
+		LocalDest:      filepath.Join(tmp, "clone"),
+	}
+	if err := gitCloneRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	if repo.Revision != pinned {
+		t.Fatalf("expected revision %s but got %s", pinned, repo.Revision)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(repo.LocalDest, "lib", "version"))
+	if err != nil || string(content) != "1" {
+		t.Fatalf("expected submodule at the pinned revision but got %q: %v", string(content), err)
+	}
+}
+
+func TestCheckCleanWorktree(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCheckCleanWorktree")
+	defer os.RemoveAll(tmp)
+	r, err := git.PlainInit(tmp, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(tmp, "main.go"), []byte("package main"), 0600)
+	tree, _ := r.Worktree()
+	if _, err := tree.Add("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "gaia", Email: "gaia@example.com", When: time.Now()}
+	if _, err := tree.Commit("initial commit", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkCleanWorktree(tmp); err != nil {
+		t.Fatalf("expected clean working tree but got: %v", err)
+	}
+
+	// Changed and untracked files are listed
+	_ = ioutil.WriteFile(filepath.Join(tmp, "main.go"), []byte("package other"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(tmp, "untracked.go"), []byte("package main"), 0600)
+	err = checkCleanWorktree(tmp)
+	if err == nil || err.Error() != "git working tree is dirty: main.go, untracked.go" {
+		t.Fatalf("expected dirty working tree error but got: %v", err)
+	}
+}
+
 func TestUpdateAllPipelinesRepositoryNotFound(t *testing.T) {
 	tmp, _ := ioutil.TempDir("", "TestUpdateAllPipelinesRepositoryNotFound")
 	gaia.Cfg = new(gaia.Config)
@@ -43,8 +143,8 @@ func TestUpdateAllPipelinesRepositoryNotFound(t *testing.T) {
 
 	p := new(gaia.Pipeline)
 	p.Repo = &gaia.GitRepo{LocalDest: tmp}
-	GlobalActivePipelines = NewActivePipelines()
-	GlobalActivePipelines.Append(*p)
+	SetGlobalActivePipelines(NewActivePipelines())
+	GetGlobalActivePipelines().Append(*p)
 	updateAllCurrentPipelines()
 	if !strings.Contains(b.String(), "repository does not exist") {
 		t.Fatal("error message not found in logs: ", b.String())
@@ -79,8 +179,8 @@ func TestUpdateAllPipelinesAlreadyUpToDate(t *testing.T) {
 	p.Repo = &gaia.GitRepo{}
 	p.Repo.SelectedBranch = "refs/heads/master"
 	p.Repo.LocalDest = "tmp"
-	GlobalActivePipelines = NewActivePipelines()
-	GlobalActivePipelines.Append(*p)
+	SetGlobalActivePipelines(NewActivePipelines())
+	GetGlobalActivePipelines().Append(*p)
 	updateAllCurrentPipelines()
 	if !strings.Contains(b.String(), "already up-to-date") {
 		t.Fatal("log output did not contain error message that the repo is up-to-date.: ", b.String())
@@ -177,8 +277,8 @@ Xbs5AQIEIzWnmQIFAOEml+E=
 	p.Repo = &gaia.GitRepo{}
 	p.Repo.SelectedBranch = "refs/heads/master"
 	p.Repo.LocalDest = "tmp"
-	GlobalActivePipelines = NewActivePipelines()
-	GlobalActivePipelines.Append(*p)
+	SetGlobalActivePipelines(NewActivePipelines())
+	GetGlobalActivePipelines().Append(*p)
 	hostConfig = "invalid.com,192.30.252.130 ssh-rsa AAAAB3NzaC1yc2EAAAABIwAAAQEAq2A7hRGmdnm9tUDbO9IDSwBK6TbQa+PXYPCPy6rbTrTtw7PHkccKrpp0yVhp5HdEIcKr6pLlVDBfOLX9QUsyCOV0wzfjIJNlGEYsdlLJizHhbn2mUjvSAHQqZETYP81eFzLQNnPHt4EVVUh7VfDESU84KezmD5QlWpXLmvU31/yMf+Se8xhHTvKSCZIFImWwoG6mbUoWf9nzpIoaSjB+weqqUUmpaaasXVal72J+UX2B+2RPW3RcT0eOzQgqlJL3RKrTJvdsjE3JEAvGq3lGHSZXy28G3skua2SmVi/w4yCE6gbODqnTWlg7+wC604ydGXA8VJiS5ap43JXiUFFAaQ=="
 	err = ioutil.WriteFile(knownHostsLocation, []byte(hostConfig), gaia.ExecutablePermission)
 	if err != nil {
@@ -224,10 +324,11 @@ func TestUpdateAllPipelinesAlreadyUpToDateWithMoreThanOnePipeline(t *testing.T)
 	p2.Repo = &gaia.GitRepo{}
 	p2.Repo.SelectedBranch = "refs/heads/master"
 	p2.Repo.LocalDest = "tmp"
-	GlobalActivePipelines = NewActivePipelines()
-	defer func() { GlobalActivePipelines = nil }()
-	GlobalActivePipelines.Append(*p1)
-	GlobalActivePipelines.Append(*p2)
+	active := GetGlobalActivePipelines()
+	defer SetGlobalActivePipelines(active)
+	SetGlobalActivePipelines(NewActivePipelines())
+	GetGlobalActivePipelines().Append(*p1)
+	GetGlobalActivePipelines().Append(*p2)
 	updateAllCurrentPipelines()
 	if !strings.Contains(b.String(), "already up-to-date") {
 		t.Fatal("log output did not contain error message that the repo is up-to-date.: ", b.String())
@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// defaultIdempotencyWindow is the time an idempotency key is remembered
+// after its build has been finished if no window has been configured.
+const defaultIdempotencyWindow = 10 * time.Minute
+
+// idempotentBuild is the build which has claimed an idempotency key.
+type idempotentBuild struct {
+	id string
+
+	// finished is the time the build has been finished.
+	// It is zero while the build is in flight.
+	finished time.Time
+}
+
+var (
+	// idempotentBuilds maps the scoped idempotency keys to the builds
+	// which claimed them. See idempotencyScope.
+	idempotentBuilds = map[string]*idempotentBuild{}
+
+	// idempotentBuildsLock protects the idempotent builds.
+	idempotentBuildsLock sync.Mutex
+)
+
+// idempotencyWindow returns the time an idempotency key is remembered
+// after its build has been finished.
+func idempotencyWindow() time.Duration {
+	if gaia.Cfg.BuildIdempotencyWindow > 0 {
+		return gaia.Cfg.BuildIdempotencyWindow
+	}
+	return defaultIdempotencyWindow
+}
+
+// idempotencyScope scopes the given idempotency key to the pipeline with
+// the given name. Builds of different pipelines never share a key.
+func idempotencyScope(name, key string) string {
+	return name + "\x00" + key
+}
+
+// ClaimIdempotencyKey claims the given idempotency key of the pipeline with
+// the given name for the build with the given ID. If the key has already
+// been claimed by a build of the pipeline which is in flight or has been
+// finished recently, the ID of that build is returned together with false.
+// Empty keys are never claimed.
+func ClaimIdempotencyKey(name, key, id string) (string, bool) {
+	if key == "" {
+		return id, true
+	}
+
+	idempotentBuildsLock.Lock()
+	defer idempotentBuildsLock.Unlock()
+
+	// Forget expired keys
+	now := time.Now()
+	window := idempotencyWindow()
+	for k, b := range idempotentBuilds {
+		if !b.finished.IsZero() && now.Sub(b.finished) > window {
+			delete(idempotentBuilds, k)
+		}
+	}
+
+	scoped := idempotencyScope(name, key)
+	if b, ok := idempotentBuilds[scoped]; ok {
+		return b.id, false
+	}
+	idempotentBuilds[scoped] = &idempotentBuild{id: id}
+	return id, true
+}
+
+// ReleaseIdempotencyKey releases the given idempotency key of the pipeline
+// with the given name so it can be claimed again, e.g. if the build has
+// been rejected.
+func ReleaseIdempotencyKey(name, key string) {
+	idempotentBuildsLock.Lock()
+	defer idempotentBuildsLock.Unlock()
+
+	delete(idempotentBuilds, idempotencyScope(name, key))
+}
+
+// finishIdempotencyKey starts the expiry window of the given idempotency
+// key of the pipeline with the given name.
+func finishIdempotencyKey(name, key string) {
+	idempotentBuildsLock.Lock()
+	defer idempotentBuildsLock.Unlock()
+
+	if b, ok := idempotentBuilds[idempotencyScope(name, key)]; ok {
+		b.finished = time.Now()
+	}
+}
@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.BuildIdempotencyWindow = time.Hour
+	defer ReleaseIdempotencyKey("main", "push-1")
+
+	if id, ok := ClaimIdempotencyKey("main", "push-1", "first"); !ok || id != "first" {
+		t.Fatalf("expected key to be claimed by 'first' but got '%s' (claimed: %v)", id, ok)
+	}
+
+	// In flight and recently finished builds are returned
+	if id, ok := ClaimIdempotencyKey("main", "push-1", "second"); ok || id != "first" {
+		t.Fatalf("expected build 'first' but got '%s' (claimed: %v)", id, ok)
+	}
+	finishIdempotencyKey("main", "push-1")
+	if id, ok := ClaimIdempotencyKey("main", "push-1", "second"); ok || id != "first" {
+		t.Fatalf("expected build 'first' but got '%s' (claimed: %v)", id, ok)
+	}
+
+	// The key expires after the window
+	idempotentBuildsLock.Lock()
+	idempotentBuilds[idempotencyScope("main", "push-1")].finished = time.Now().Add(-2 * time.Hour)
+	idempotentBuildsLock.Unlock()
+	if id, ok := ClaimIdempotencyKey("main", "push-1", "third"); !ok || id != "third" {
+		t.Fatalf("expected expired key to be claimed by 'third' but got '%s' (claimed: %v)", id, ok)
+	}
+
+	// Released keys can be claimed again
+	ReleaseIdempotencyKey("main", "push-1")
+	if _, ok := ClaimIdempotencyKey("main", "push-1", "fourth"); !ok {
+		t.Fatal("expected released key to be claimed")
+	}
+
+	// Keys are scoped to the pipeline
+	defer ReleaseIdempotencyKey("other", "push-1")
+	if id, ok := ClaimIdempotencyKey("other", "push-1", "fifth"); !ok || id != "fifth" {
+		t.Fatalf("expected key of another pipeline to be claimed by 'fifth' but got '%s' (claimed: %v)", id, ok)
+	}
+
+	// Requests without key are never deduplicated
+	for _, id := range []string{"a", "b"} {
+		if got, ok := ClaimIdempotencyKey("main", "", id); !ok || got != id {
+			t.Fatalf("expected empty key to be claimed by '%s' but got '%s' (claimed: %v)", id, got, ok)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gaia-pipeline/gaia"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// manifestFiles are the names of the pipeline manifest in the source
+// root, in the order they are looked up.
+var manifestFiles = []string{"gaia.yaml", "gaia.json"}
+
+// readManifest reads the metadata from the manifest in the given source
+// root. No metadata is returned if there is no manifest.
+func readManifest(root string) (*gaia.PipelineMetadata, error) {
+	for _, name := range manifestFiles {
+		content, err := ioutil.ReadFile(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m := &gaia.PipelineMetadata{}
+		if filepath.Ext(name) == ".json" {
+			err = json.Unmarshal(content, m)
+		} else {
+			err = yaml.Unmarshal(content, m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest %s: %s", name, err.Error())
+		}
+		return m, nil
+	}
+	return nil, nil
+}
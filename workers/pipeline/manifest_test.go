@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestReadManifest")
+	defer os.RemoveAll(tmp)
+
+	// A missing manifest is not an error
+	m, err := readManifest(tmp)
+	if err != nil || m != nil {
+		t.Fatalf("expected no metadata without manifest but got %v: %v", m, err)
+	}
+
+	_ = ioutil.WriteFile(filepath.Join(tmp, "gaia.json"), []byte(`{"description": "from json", "owner": "json"}`), 0600)
+	m, err = readManifest(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Description != "from json" || m.Owner != "json" {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+
+	// The yaml manifest is preferred
+	manifest := "description: Deploys the api\ntags:\n  - api\n  - deploy\nowner: platform-team\n"
+	_ = ioutil.WriteFile(filepath.Join(tmp, "gaia.yaml"), []byte(manifest), 0600)
+	m, err = readManifest(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Description != "Deploys the api" || m.Owner != "platform-team" || strings.Join(m.Tags, ",") != "api,deploy" {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+
+	_ = ioutil.WriteFile(filepath.Join(tmp, "gaia.yaml"), []byte("tags: ["), 0600)
+	if _, err := readManifest(tmp); err == nil || !strings.Contains(err.Error(), "invalid manifest gaia.yaml") {
+		t.Fatalf("expected invalid manifest error but got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+)
+
+// BuildNotification is sent to all registered notifiers after a build.
+type BuildNotification struct {
+	Pipeline string
+	Type     gaia.PipelineType
+	Status   gaia.CreatePipelineType
+	Revision string
+	Output   string
+}
+
+// Notifier sends build notifications e.g. to a webhook or a chat.
+type Notifier interface {
+	// Notify sends the given notification.
+	Notify(n BuildNotification) error
+}
+
+var (
+	// notifiers are all registered notifiers.
+	notifiers []Notifier
+
+	// notifiersLock protects the registered notifiers.
+	notifiersLock sync.RWMutex
+
+	// notificationPolicyLock is held for reading while a notification is
+	// dispatched and for writing while a notification policy is changed.
+	// Once the policy of a pipeline has been changed, no in-flight
+	// notification with the old policy is sent anymore.
+	notificationPolicyLock sync.RWMutex
+)
+
+// RegisterNotifier registers a notifier which receives all build
+// notifications permitted by the notification policy of the pipeline.
+func RegisterNotifier(n Notifier) {
+	notifiersLock.Lock()
+	defer notifiersLock.Unlock()
+
+	notifiers = append(notifiers, n)
+}
+
+// SetNotificationPolicy sets the notification policy of the active
+// pipeline with the given name and stores the pipeline.
+func SetNotificationPolicy(name string, policy gaia.NotificationPolicy) error {
+	switch policy {
+	case gaia.NotifyAlways, gaia.NotifyOnFailure, gaia.NotifyMuted:
+	default:
+		return fmt.Errorf("unknown notification policy %s", policy)
+	}
+
+	notificationPolicyLock.Lock()
+	defer notificationPolicyLock.Unlock()
+
+	var updated gaia.Pipeline
+	found := GetGlobalActivePipelines().UpdateByName(name, func(p *gaia.Pipeline) {
+		p.NotificationPolicy = policy
+		updated = *p
+	})
+	if !found {
+		return fmt.Errorf("cannot find pipeline %s", name)
+	}
+	storeService, err := services.StorageService()
+	if err != nil {
+		return err
+	}
+	return storeService.PipelinePut(&updated)
+}
+
+// shouldNotify checks if the given policy permits a notification
+// for a build with the given status.
+func shouldNotify(policy gaia.NotificationPolicy, status gaia.CreatePipelineType) bool {
+	switch policy {
+	case gaia.NotifyAlways:
+		return true
+	case gaia.NotifyMuted:
+		return false
+	default:
+		return status == gaia.CreatePipelineFailed
+	}
+}
+
+// notifyBuild sends the notification of the given build to all registered
+// notifiers if the notification policy of the pipeline permits it. The
+// policy of the active pipeline takes precedence over the policy of the
+// build because it might have been changed during the build.
+func notifyBuild(p *gaia.CreatePipeline) {
+	notificationPolicyLock.RLock()
+	defer notificationPolicyLock.RUnlock()
+
+	policy := p.Pipeline.NotificationPolicy
+	if active := GetGlobalActivePipelines().GetByName(p.Pipeline.Name); active != nil {
+		policy = active.NotificationPolicy
+	}
+	if !shouldNotify(policy, p.StatusType) {
+		return
+	}
+
+	n := BuildNotification{
+		Pipeline: p.Pipeline.Name,
+		Type:     p.Pipeline.Type,
+		Status:   p.StatusType,
+		Output:   p.Output,
+	}
+	if p.Pipeline.Repo != nil {
+		n.Revision = p.Pipeline.Repo.Revision
+	}
+
+	notifiersLock.RLock()
+	defer notifiersLock.RUnlock()
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(n); err != nil {
+			gaia.Cfg.Logger.Error("cannot send build notification", "pipeline", n.Pipeline, "error", err.Error())
+		}
+	}
+}
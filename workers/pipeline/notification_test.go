@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type recordingNotifier struct {
+	notifications []BuildNotification
+}
+
+func (r *recordingNotifier) Notify(n BuildNotification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		policy   gaia.NotificationPolicy
+		status   gaia.CreatePipelineType
+		expected bool
+	}{
+		{"", gaia.CreatePipelineFailed, true},
+		{"", gaia.CreatePipelineSuccess, false},
+		{gaia.NotifyOnFailure, gaia.CreatePipelineUpToDate, false},
+		{gaia.NotifyAlways, gaia.CreatePipelineSuccess, true},
+		{gaia.NotifyAlways, gaia.CreatePipelineFailed, true},
+		{gaia.NotifyMuted, gaia.CreatePipelineFailed, false},
+	}
+	for _, test := range tests {
+		if notify := shouldNotify(test.policy, test.status); notify != test.expected {
+			t.Fatalf("expected %t for policy '%s' and status '%s' but got %t", test.expected, test.policy, test.status, notify)
+		}
+	}
+}
+
+func TestNotifyBuild(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	services.MockStorageService(new(nodeJSMockStorer))
+	defer services.MockStorageService(nil)
+	SetGlobalActivePipelines(NewActivePipelines())
+
+	notifier := &recordingNotifier{}
+	RegisterNotifier(notifier)
+	defer func() {
+		notifiersLock.Lock()
+		notifiers = nil
+		notifiersLock.Unlock()
+	}()
+
+	p := &gaia.CreatePipeline{StatusType: gaia.CreatePipelineFailed}
+	p.Pipeline.Name = "TestNotifyBuild"
+	p.Pipeline.Repo = &gaia.GitRepo{Revision: "abc"}
+	notifyBuild(p)
+	if len(notifier.notifications) != 1 || notifier.notifications[0].Revision != "abc" {
+		t.Fatalf("expected notification of the failed build but got %v", notifier.notifications)
+	}
+
+	// The policy of the active pipeline takes precedence
+	GetGlobalActivePipelines().Append(p.Pipeline)
+	if err := SetNotificationPolicy(p.Pipeline.Name, gaia.NotifyMuted); err != nil {
+		t.Fatal(err)
+	}
+	notifyBuild(p)
+	if len(notifier.notifications) != 1 {
+		t.Fatal("muted pipeline should not send notifications")
+	}
+
+	if err := SetNotificationPolicy(p.Pipeline.Name, "sometimes"); err == nil {
+		t.Fatal("expected error for unknown notification policy")
+	}
+	if err := SetNotificationPolicy("unknown", gaia.NotifyAlways); err == nil {
+		t.Fatal("expected error for unknown pipeline")
+	}
+}
@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/gaia-pipeline/gaia"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// validatePathFilters checks the syntax of the path filters of the given pipeline.
+func validatePathFilters(p *gaia.CreatePipeline) error {
+	for _, pattern := range p.PathFilters {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid path filter %s: %s", pattern, err.Error())
+		}
+	}
+	return nil
+}
+
+// matchPathFilters checks if the given file matches one of the path filters.
+// A pattern which matches a folder matches all files inside of the folder.
+func matchPathFilters(filters []string, file string) bool {
+	for _, pattern := range filters {
+		for name := file; name != "." && name != "/"; name = path.Dir(name) {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathFiltersUnchanged checks if the build of the given pipeline can be
+// skipped because no file which matches the path filters has changed since
+// the last build. The pipeline is built if the previous revision or its
+// binary is unknown, the sources have not been fetched with git, or the
+// options of the build differ from the ones of the last build.
+func pathFiltersUnchanged(p *gaia.CreatePipeline) bool {
+	if len(p.PathFilters) == 0 || p.SourcePath != "" || p.Image != nil {
+		return false
+	}
+	if p.VCS != "" && p.VCS != gaia.VCSGit {
+		return false
+	}
+	active := GetGlobalActivePipelines().GetByName(p.Pipeline.Name)
+	if active == nil || active.Type != p.Pipeline.Type || active.Repo == nil || active.Repo.Revision == "" {
+		return false
+	}
+	if !specUnchanged(p, active.Fingerprint) {
+		return false
+	}
+	if _, err := os.Stat(binaryDestination(p)); err != nil {
+		return false
+	}
+
+	match, err := pathFiltersMatch(p.PathFilters, p.Pipeline.Repo.LocalDest, active.Repo.Revision, p.Pipeline.Repo.Revision)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot diff revisions for path filters", "pipeline", p.Pipeline.Name, "error", err.Error())
+		return false
+	}
+	return !match
+}
+
+// updateUnchanged checks if the given rebuild of a pipeline can be skipped
+// after its repository has been updated from the given revision, because
+// no file which matches the path filters of the pipeline has changed and
+// the options of the build equal the ones of the last build.
+func updateUnchanged(p *gaia.CreatePipeline, r *git.Repository, from plumbing.Hash) bool {
+	pipe := &p.Pipeline
+	if len(pipe.PathFilters) == 0 || from.IsZero() || !specUnchanged(p, pipe.Fingerprint) {
+		return false
+	}
+	head, err := r.Head()
+	if err != nil {
+		return false
+	}
+	match, err := pathFiltersMatch(pipe.PathFilters, pipe.Repo.LocalDest, from.String(), head.Hash().String())
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot diff revisions for path filters", "pipeline", pipe.Name, "error", err.Error())
+		return false
+	}
+	return !match
+}
+
+// pathFiltersMatch checks if one of the files which differ between the given
+// revisions of the git repository in the given folder matches the filters.
+func pathFiltersMatch(filters []string, dir, from, to string) (bool, error) {
+	files, err := changedFiles(dir, from, to)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range files {
+		if matchPathFilters(filters, file) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// changedFiles returns all files which differ between the given revisions
+// of the git repository in the given folder.
+func changedFiles(dir, from, to string) ([]string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	fromCommit, err := r.CommitObject(plumbing.NewHash(from))
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := r.CommitObject(plumbing.NewHash(to))
+	if err != nil {
+		return nil, err
+	}
+	fromTree, err := fromCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := toCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := fromTree.Diff(toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, c := range changes {
+		// Renamed files match with both names
+		if c.From.Name != "" {
+			files = append(files, c.From.Name)
+		}
+		if c.To.Name != "" && c.To.Name != c.From.Name {
+			files = append(files, c.To.Name)
+		}
+	}
+	return files, nil
+}
+
+// specUnchanged checks if the options of the given build equal the ones
+// of the build with the given fingerprint.
+func specUnchanged(p *gaia.CreatePipeline, f *gaia.BuildFingerprint) bool {
+	if f == nil {
+		return false
+	}
+	spec, err := buildSpec(p)
+	return err == nil && spec == f.SpecHash
+}
@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+	hclog "github.com/hashicorp/go-hclog"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// commitFiles writes the given files into the repository and commits them.
+func commitFiles(t *testing.T, r *git.Repository, dir string, files map[string]string) string {
+	tree, _ := r.Worktree()
+	for name, content := range files {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700)
+		_ = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if _, err := tree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "gaia", Email: "gaia@example.com", When: time.Now()}
+	hash, err := tree.Commit("commit", &git.CommitOptions{Author: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash.String()
+}
+
+func TestMatchPathFilters(t *testing.T) {
+	filters := []string{"services/api", "*.mod", "docs/*.md"}
+	for file, expected := range map[string]bool{
+		"services/api/main.go": true,
+		"go.mod":               true,
+		"docs/index.md":        true,
+		"docs/img/logo.png":    false,
+		"services/web/main.go": false,
+	} {
+		if matchPathFilters(filters, file) != expected {
+			t.Fatalf("expected match of %s to be %v", file, expected)
+		}
+	}
+
+	if err := validatePathFilters(&gaia.CreatePipeline{PathFilters: []string{"[a-"}}); err == nil {
+		t.Fatal("expected invalid pattern to be rejected")
+	}
+}
+
+func TestPathFiltersUnchanged(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPathFiltersUnchanged")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	gaia.Cfg.PipelinePath = tmp
+	active := GetGlobalActivePipelines()
+	defer SetGlobalActivePipelines(active)
+	SetGlobalActivePipelines(NewActivePipelines())
+
+	src := filepath.Join(tmp, "src")
+	r, err := git.PlainInit(src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitFiles(t, r, src, map[string]string{"api/main.go": "package main", "README.md": "readme"})
+	second := commitFiles(t, r, src, map[string]string{"README.md": "changed"})
+
+	p := &gaia.CreatePipeline{PathFilters: []string{"api"}}
+	p.Pipeline = gaia.Pipeline{Name: "api", Type: gaia.PTypeGolang, Repo: &gaia.GitRepo{LocalDest: src, Revision: second}}
+
+	// Without a previous build the pipeline is built
+	if pathFiltersUnchanged(p) {
+		t.Fatal("expected build without previous build")
+	}
+	spec, err := buildSpec(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetGlobalActivePipelines().Append(gaia.Pipeline{Name: "api", Type: gaia.PTypeGolang, Repo: &gaia.GitRepo{Revision: first}, Fingerprint: &gaia.BuildFingerprint{SpecHash: spec}})
+	if pathFiltersUnchanged(p) {
+		t.Fatal("expected build without existing binary")
+	}
+
+	_ = ioutil.WriteFile(binaryDestination(p), []byte("binary"), 0700)
+	if !pathFiltersUnchanged(p) {
+		t.Fatal("expected build to be skipped if only unfiltered files changed")
+	}
+
+	// Changed build options are built
+	p.Lint = true
+	if pathFiltersUnchanged(p) {
+		t.Fatal("expected build if the build options changed")
+	}
+	p.Lint = false
+
+	third := commitFiles(t, r, src, map[string]string{"api/handler.go": "package main"})
+	p.Pipeline.Repo.Revision = third
+	if pathFiltersUnchanged(p) {
+		t.Fatal("expected build if a filtered file changed")
+	}
+
+	// Unknown revisions cannot be diffed
+	GetGlobalActivePipelines().UpdateByName("api", func(a *gaia.Pipeline) { a.Repo.Revision = "0123456789abcdef0123456789abcdef01234567" })
+	p.Pipeline.Repo.Revision = second
+	if pathFiltersUnchanged(p) {
+		t.Fatal("expected build if the previous revision is unknown")
+	}
+}
+
+// updateBuildPipeline records the build requests of repository updates.
+type updateBuildPipeline struct {
+	BuildPipelineGolang
+	built *gaia.CreatePipeline
+}
+
+func (b *updateBuildPipeline) ExecuteBuild(p *gaia.CreatePipeline) error {
+	b.built = p
+	p.Pipeline.ExecPath = filepath.Join(p.Pipeline.Repo.LocalDest, golangOutputName(p))
+	return ioutil.WriteFile(p.Pipeline.ExecPath, []byte("binary"), 0700)
+}
+
+func (b *updateBuildPipeline) SavePipeline(p *gaia.Pipeline) error {
+	p.ExecPath = GetExecPath(*p)
+	return nil
+}
+
+func TestUpdateRepositoryPathFilters(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestUpdateRepositoryPathFilters")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	gaia.Cfg.HomePath = tmp
+	gaia.Cfg.PipelinePath = tmp
+	services.MockStorageService(new(mockCreatePipelineStore))
+	defer services.MockStorageService(nil)
+	services.MockSchedulerService(new(mockScheduler))
+	defer services.MockSchedulerService(nil)
+
+	mockType := gaia.PipelineType("update")
+	bP := &updateBuildPipeline{}
+	RegisterBuildPipeline(mockType, func() BuildPipeline { return bP })
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+
+	origin := filepath.Join(tmp, "origin")
+	r, err := git.PlainInit(origin, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, r, origin, map[string]string{"api/main.go": "package main", "README.md": "readme"})
+
+	pipe := &gaia.Pipeline{
+		Name:         "api",
+		Type:         mockType,
+		PathFilters:  []string{"api"},
+		Repo:         &gaia.GitRepo{URL: origin, LocalDest: filepath.Join(tmp, "clone"), SelectedBranch: "refs/heads/master"},
+		BuildOptions: &gaia.BuildOptions{Lint: true, Artifacts: []string{"api/*.go"}},
+	}
+	if err := gitCloneRepo(pipe.Repo); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := buildSpec(updateRequest(pipe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe.Fingerprint = &gaia.BuildFingerprint{SpecHash: spec}
+
+	// Changes of unfiltered files are pulled but not built
+	commitFiles(t, r, origin, map[string]string{"README.md": "changed"})
+	if err := UpdateRepository(pipe); err != nil {
+		t.Fatal(err)
+	}
+	if bP.built != nil {
+		t.Fatal("expected update without build")
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(pipe.Repo.LocalDest, "README.md"))
+	if string(content) != "changed" {
+		t.Fatalf("expected pulled README but got %q", string(content))
+	}
+
+	// Changed build options are built although no filtered file changed
+	pipe.BuildOptions.Lint = false
+	commitFiles(t, r, origin, map[string]string{"README.md": "changed again"})
+	if err := UpdateRepository(pipe); err != nil {
+		t.Fatal(err)
+	}
+	if bP.built == nil || bP.built.Lint {
+		t.Fatalf("expected build with the changed build options but got %+v", bP.built)
+	}
+	pipe.BuildOptions.Lint = true
+	bP.built = nil
+
+	// Changes of filtered files are built with the stored build options
+	commitFiles(t, r, origin, map[string]string{"api/handler.go": "package main"})
+	if err := UpdateRepository(pipe); err != nil {
+		t.Fatal(err)
+	}
+	if bP.built == nil || !bP.built.Lint || len(bP.built.Artifacts) != 1 || len(bP.built.PathFilters) != 1 {
+		t.Fatalf("expected build with the stored build options but got %+v", bP.built)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "api_update.meta.json")); err != nil {
+		t.Fatalf("expected installed pipeline: %v", err)
+	}
+
+	// Failed builds are reported
+	bP.built = nil
+	pipe.BuildOptions.Artifacts = []string{"missing/*"}
+	commitFiles(t, r, origin, map[string]string{"api/handler.go": "package main // changed"})
+	if err := UpdateRepository(pipe); err == nil || !strings.Contains(err.Error(), "cannot find artifacts") {
+		t.Fatalf("expected error of the failed build but got %v", err)
+	}
+}
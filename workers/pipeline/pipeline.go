@@ -1,13 +1,20 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -43,63 +50,200 @@ type ActivePipelines struct {
 }
 
 const (
-	// Max minutes until the build process will be interrupted and marked as failed
-	maxTimeoutMinutes = 60
+	// Default time until the prepare phase will be interrupted and marked as failed
+	defaultPrepareTimeout = 20 * time.Minute
+
+	// Default time until the compile phase will be interrupted and marked as failed
+	defaultCompileTimeout = 40 * time.Minute
+
+	// Percent of the timeout of a build phase after which a warning is logged
+	timeoutWarningPercent = 80
+
+	// Default maximum size of an installed pipeline artifact in bytes
+	defaultMaxArtifactSize = 1 << 30
 
 	// typeDelimiter defines the delimiter in the file name to define
 	// the pipeline type.
 	typeDelimiter = "_"
+
+	// windowsExecutableExtension is required to execute binaries on windows.
+	windowsExecutableExtension = ".exe"
+
+	// wasmExtension marks WebAssembly binaries which are started
+	// in the WebAssembly runtime instead of being executed.
+	wasmExtension = ".wasm"
 )
 
 var (
 	// GlobalActivePipelines holds globally all current active pipleines.
+	//
+	// Deprecated: The variable is nil until it has been initialized. Use
+	// GetGlobalActivePipelines and SetGlobalActivePipelines instead.
 	GlobalActivePipelines *ActivePipelines
 
+	// globalActivePipelinesOnce initializes the global active pipelines.
+	globalActivePipelinesOnce sync.Once
+
 	// errMissingType is the error thrown when a pipeline is missing the type
 	// in the file name.
 	errMissingType = errors.New("couldnt find pipeline type definition")
+
+	// errMissingName is the error thrown when a file name of a pipeline
+	// contains the type but no pipeline name.
+	errMissingName = errors.New("couldnt find pipeline name definition")
+
+	// errNilCreatePipeline is thrown when a build pipeline gets no create pipeline spec.
+	errNilCreatePipeline = errors.New("create pipeline spec must not be nil")
 )
 
 // execution command context used for build
 var execCommandContext = exec.CommandContext
 
+// hostOS is the operating system Gaia is running on.
+var hostOS = runtime.GOOS
+
 // Source folder name where the sources are stored
 const srcFolder = "src"
 
+// Folder name inside the pipelines folder where build matrix results are stored
+const matrixFolder = "matrix"
+
+// BuildPipelineFactory creates a new build pipeline instance.
+type BuildPipelineFactory func() BuildPipeline
+
+var (
+	// buildPipelineFactories holds the factories of all supported pipeline types.
+	buildPipelineFactories = map[gaia.PipelineType]BuildPipelineFactory{
+		gaia.PTypeGolang: func() BuildPipeline {
+			return &BuildPipelineGolang{Type: gaia.PTypeGolang}
+		},
+		gaia.PTypeJava: func() BuildPipeline {
+			return &BuildPipelineJava{Type: gaia.PTypeJava}
+		},
+		gaia.PTypePython: func() BuildPipeline {
+			return &BuildPipelinePython{Type: gaia.PTypePython}
+		},
+		gaia.PTypeCpp: func() BuildPipeline {
+			return &BuildPipelineCpp{Type: gaia.PTypeCpp}
+		},
+		gaia.PTypeRuby: func() BuildPipeline {
+			return &BuildPipelineRuby{Type: gaia.PTypeRuby}
+		},
+		gaia.PTypeNodeJS: func() BuildPipeline {
+			return &BuildPipelineNodeJS{Type: gaia.PTypeNodeJS}
+		},
+		gaia.PTypePerl: func() BuildPipeline {
+			return &BuildPipelinePerl{Type: gaia.PTypePerl}
+		},
+		gaia.PTypeR: func() BuildPipeline {
+			return &BuildPipelineR{Type: gaia.PTypeR}
+		},
+		gaia.PTypeHaskell: func() BuildPipeline {
+			return &BuildPipelineHaskell{Type: gaia.PTypeHaskell}
+		},
+	}
+
+	// buildPipelineFactoriesLock protects the build pipeline factories.
+	buildPipelineFactoriesLock sync.RWMutex
+)
+
+func init() {
+	gaia.SetPipelineTypeSource(registeredPipelineTypes)
+}
+
+// registeredPipelineTypes returns the sorted pipeline types which have a
+// registered build pipeline.
+func registeredPipelineTypes() []gaia.PipelineType {
+	buildPipelineFactoriesLock.RLock()
+	types := make([]gaia.PipelineType, 0, len(buildPipelineFactories))
+	for t := range buildPipelineFactories {
+		types = append(types, t)
+	}
+	buildPipelineFactoriesLock.RUnlock()
+
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// RegisterBuildPipeline registers the given factory for the given pipeline
+// type. An already registered factory for this type will be replaced.
+// This allows adding support for new pipeline types as well as
+// substituting existing build pipelines e.g. in tests.
+func RegisterBuildPipeline(t gaia.PipelineType, factory BuildPipelineFactory) {
+	buildPipelineFactoriesLock.Lock()
+	defer buildPipelineFactoriesLock.Unlock()
+
+	buildPipelineFactories[t] = factory
+}
+
 // newBuildPipeline creates a new build pipeline for the given
-// pipeline type.
+// pipeline type. Returns nil if the pipeline type is not supported.
 func newBuildPipeline(t gaia.PipelineType) BuildPipeline {
-	var bP BuildPipeline
+	buildPipelineFactoriesLock.RLock()
+	factory, ok := buildPipelineFactories[t]
+	buildPipelineFactoriesLock.RUnlock()
 
-	// Create build pipeline for given pipeline type
-	switch t {
-	case gaia.PTypeGolang:
-		bP = &BuildPipelineGolang{
-			Type: t,
-		}
-	case gaia.PTypeJava:
-		bP = &BuildPipelineJava{
-			Type: t,
-		}
-	case gaia.PTypePython:
-		bP = &BuildPipelinePython{
-			Type: t,
-		}
-	case gaia.PTypeCpp:
-		bP = &BuildPipelineCpp{
-			Type: t,
-		}
-	case gaia.PTypeRuby:
-		bP = &BuildPipelineRuby{
-			Type: t,
-		}
-	case gaia.PTypeNodeJS:
-		bP = &BuildPipelineNodeJS{
-			Type: t,
+	if !ok || factory == nil {
+		return nil
+	}
+	return factory()
+}
+
+// EnvironmentChecker is implemented by build pipelines which can check
+// the prerequisites of their build environment without doing any work.
+type EnvironmentChecker interface {
+	// CheckEnvironment returns an error if a prerequisite is missing.
+	CheckEnvironment() error
+}
+
+// CanBuild checks if the given pipeline type is supported and if the
+// prerequisites of its build environment are met. No build work is done.
+func CanBuild(t gaia.PipelineType) (bool, error) {
+	bP := newBuildPipeline(t)
+	if bP == nil {
+		return false, fmt.Errorf("pipeline type %s is not supported", t)
+	}
+	if checker, ok := bP.(EnvironmentChecker); ok {
+		if err := checker.CheckEnvironment(); err != nil {
+			return false, err
 		}
 	}
+	return true, nil
+}
+
+// CanBuildImage checks if the given pipeline type is supported and if a
+// container builder is installed. Images are built inside of the container,
+// so the toolchain of the pipeline type is not required on this host.
+func CanBuildImage(t gaia.PipelineType) (bool, error) {
+	if newBuildPipeline(t) == nil {
+		return false, fmt.Errorf("pipeline type %s is not supported", t)
+	}
+	if _, err := containerBuilder(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ValidateAllBuildEnvironments checks the build environment of all
+// registered pipeline types. The result maps each type to nil if it can be
+// built on this host or to the error which describes the missing prerequisite.
+func ValidateAllBuildEnvironments() map[gaia.PipelineType]error {
+	types := registeredPipelineTypes()
+	report := make(map[gaia.PipelineType]error, len(types))
+	for _, t := range types {
+		_, report[t] = CanBuild(t)
+	}
+	return report
+}
 
-	return bP
+// checkToolchain checks if all given binaries of a toolchain are installed.
+func checkToolchain(toolchain string, binaries ...string) error {
+	for _, binary := range binaries {
+		if _, err := exec.LookPath(binary); err != nil {
+			return fmt.Errorf("%s toolchain not installed on this server: cannot find %s", toolchain, binary)
+		}
+	}
+	return nil
 }
 
 // NewActivePipelines creates a new instance of ActivePipelines
@@ -111,6 +255,25 @@ func NewActivePipelines() *ActivePipelines {
 	return ap
 }
 
+// GetGlobalActivePipelines returns the global active pipelines. They are
+// created on first access, so they can be used before the ticker started.
+func GetGlobalActivePipelines() *ActivePipelines {
+	globalActivePipelinesOnce.Do(func() {
+		if GlobalActivePipelines == nil {
+			GlobalActivePipelines = NewActivePipelines()
+		}
+	})
+	return GlobalActivePipelines
+}
+
+// SetGlobalActivePipelines replaces the global active pipelines. It must
+// not be called concurrently with GetGlobalActivePipelines, e.g. only
+// during startup or in tests.
+func SetGlobalActivePipelines(ap *ActivePipelines) {
+	globalActivePipelinesOnce.Do(func() {})
+	GlobalActivePipelines = ap
+}
+
 // Append appends a new pipeline to ActivePipelines.
 func (ap *ActivePipelines) Append(p gaia.Pipeline) {
 	ap.Lock()
@@ -119,6 +282,28 @@ func (ap *ActivePipelines) Append(p gaia.Pipeline) {
 	ap.Pipelines = append(ap.Pipelines, p)
 }
 
+// AppendWithResult appends a pipeline to ActivePipelines and returns
+// the index the pipeline has been appended at.
+func (ap *ActivePipelines) AppendWithResult(p gaia.Pipeline) int {
+	ap.Lock()
+	defer ap.Unlock()
+
+	ap.Pipelines = append(ap.Pipelines, p)
+	return len(ap.Pipelines) - 1
+}
+
+// GetByIndex returns the active pipeline at the given index.
+// The second return value is false if the index is out of bounds.
+func (ap *ActivePipelines) GetByIndex(i int) (gaia.Pipeline, bool) {
+	ap.RLock()
+	defer ap.RUnlock()
+
+	if i < 0 || i >= len(ap.Pipelines) {
+		return gaia.Pipeline{}, false
+	}
+	return ap.Pipelines[i], true
+}
+
 // Update updates a pipeline at the given index with the given pipeline.
 func (ap *ActivePipelines) Update(index int, p gaia.Pipeline) error {
 	ap.Lock()
@@ -193,6 +378,55 @@ func (ap *ActivePipelines) ReplaceByName(n string, p gaia.Pipeline) bool {
 	return false
 }
 
+// Rename renames the pipeline with the given name and its binary while
+// holding the write lock. The build history is moved to the new name.
+// An error is returned if a pipeline with the new name already exists.
+func (ap *ActivePipelines) Rename(oldName, newName string) error {
+	ap.Lock()
+	defer ap.Unlock()
+
+	index := -1
+	for i, pipeline := range ap.Pipelines {
+		switch pipeline.Name {
+		case oldName:
+			index = i
+		case newName:
+			return fmt.Errorf("pipeline %s already exists", newName)
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("cannot find pipeline %s", oldName)
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	p := ap.Pipelines[index]
+	if err := RenameBinary(p, newName); err != nil {
+		return err
+	}
+	p.Name = newName
+	p.ExecPath = GetExecPath(p)
+	ap.Pipelines[index] = p
+	BuildHistory.rename(oldName, newName)
+	return nil
+}
+
+// UpdateByName applies the given mutation to the pipeline with the given
+// name while holding the write lock. Returns false if no pipeline was found.
+func (ap *ActivePipelines) UpdateByName(name string, mutate func(*gaia.Pipeline)) bool {
+	ap.Lock()
+	defer ap.Unlock()
+
+	for i := range ap.Pipelines {
+		if ap.Pipelines[i].Name == name {
+			mutate(&ap.Pipelines[i])
+			return true
+		}
+	}
+	return false
+}
+
 // GetAll iterates over the pipelines in the concurrent slice.
 func (ap *ActivePipelines) GetAll() []gaia.Pipeline {
 	c := make([]gaia.Pipeline, 0)
@@ -202,6 +436,22 @@ func (ap *ActivePipelines) GetAll() []gaia.Pipeline {
 	return c
 }
 
+// AsMap returns a snapshot of all pipelines keyed by name. Callers can keep
+// the snapshot for repeated lookups. If several pipelines share a name the
+// first one is used like in GetByName.
+func (ap *ActivePipelines) AsMap() map[string]gaia.Pipeline {
+	ap.RLock()
+	defer ap.RUnlock()
+
+	m := make(map[string]gaia.Pipeline, len(ap.Pipelines))
+	for _, p := range ap.Pipelines {
+		if _, ok := m[p.Name]; !ok {
+			m[p.Name] = p
+		}
+	}
+	return m
+}
+
 // Contains checks if the given pipeline name has been already appended
 // to the given ActivePipelines instance.
 func (ap *ActivePipelines) Contains(n string) bool {
@@ -238,43 +488,541 @@ func (ap *ActivePipelines) RemoveDeletedPipelines(existingPipelineNames []string
 	}
 }
 
+// ReconcileWithDir scans the given plugins folder and reconciles the result
+// with ActivePipelines. Pipelines which are found in the folder but are not
+// active yet will be added. Active pipelines whose binary vanished from the
+// folder will be removed. Files which do not follow the type naming scheme
+// are skipped. The names of the added and removed pipelines are returned.
+func (ap *ActivePipelines) ReconcileWithDir(path string) (added, removed []string, err error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Collect all pipelines which are present in the folder.
+	found := make(map[string]gaia.Pipeline)
+	for _, file := range files {
+		if file.IsDir() || isBinaryMetadata(file.Name()) || isSourceArchive(file.Name()) {
+			continue
+		}
+
+		// Get pipeline name and type
+		pName, pType, err := parseBinaryName(path, strings.TrimSpace(file.Name()))
+		if err != nil {
+			continue
+		}
+		found[pName] = gaia.Pipeline{
+			Name:     pName,
+			Type:     pType,
+			ExecPath: filepath.Join(path, file.Name()),
+			Created:  time.Now(),
+			Tags:     []string{pType.String()},
+
+			PluginModule: isPluginModule(path, file.Name()),
+		}
+	}
+
+	// Hold the lock for the whole update so concurrent builds
+	// cannot interleave with the reconciliation.
+	ap.Lock()
+	defer ap.Unlock()
+
+	active := make(map[string]bool, len(ap.Pipelines))
+	pipelines := make([]gaia.Pipeline, 0, len(ap.Pipelines))
+	for _, pipeline := range ap.Pipelines {
+		active[pipeline.Name] = true
+		if _, ok := found[pipeline.Name]; !ok {
+			removed = append(removed, pipeline.Name)
+			continue
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+
+	// Sort new pipelines by name to get a stable order.
+	var newNames []string
+	for name := range found {
+		if !active[name] {
+			newNames = append(newNames, name)
+		}
+	}
+	sort.Strings(newNames)
+	for _, name := range newNames {
+		pipelines = append(pipelines, found[name])
+		added = append(added, name)
+	}
+	ap.Pipelines = pipelines
+	return added, removed, nil
+}
+
+// TotalBinarySize returns the summed size in bytes of all active pipeline
+// binaries. Missing binaries are skipped and reported in the returned error
+// while the size of all found binaries is still returned.
+func (ap *ActivePipelines) TotalBinarySize() (int64, error) {
+	ap.RLock()
+	defer ap.RUnlock()
+
+	var size int64
+	var missing []string
+	for _, pipeline := range ap.Pipelines {
+		execPath := pipeline.ExecPath
+		if execPath == "" {
+			execPath = GetExecPath(pipeline)
+		}
+
+		info, err := os.Stat(execPath)
+		if err != nil {
+			missing = append(missing, execPath)
+			continue
+		}
+		size += info.Size()
+	}
+
+	if len(missing) > 0 {
+		return size, fmt.Errorf("cannot find pipeline binaries: %s", strings.Join(missing, ", "))
+	}
+	return size, nil
+}
+
+// Diff compares the active pipelines with the given desired pipelines.
+// Pipelines are matched by name. Desired pipelines which are not active are
+// returned in toAdd, desired pipelines with a changed spec in toUpdate and
+// active pipelines which are not desired in toRemove.
+func (ap *ActivePipelines) Diff(desired []gaia.Pipeline) (toAdd, toUpdate, toRemove []gaia.Pipeline) {
+	ap.RLock()
+	defer ap.RUnlock()
+
+	current := make(map[string]gaia.Pipeline, len(ap.Pipelines))
+	for _, pipeline := range ap.Pipelines {
+		current[pipeline.Name] = pipeline
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, pipeline := range desired {
+		wanted[pipeline.Name] = true
+		active, ok := current[pipeline.Name]
+		switch {
+		case !ok:
+			toAdd = append(toAdd, pipeline)
+		case pipelineSpecChanged(active, pipeline):
+			toUpdate = append(toUpdate, pipeline)
+		}
+	}
+
+	for _, pipeline := range ap.Pipelines {
+		if !wanted[pipeline.Name] {
+			toRemove = append(toRemove, pipeline)
+		}
+	}
+	return
+}
+
+// pipelineSpecChanged checks if the spec of the desired pipeline differs
+// from the active pipeline. Only fields which are defined by the user count
+// as a meaningful change. Generated fields like the ID, the exec path or
+// the jobs are ignored.
+func pipelineSpecChanged(active, desired gaia.Pipeline) bool {
+	if active.Type != desired.Type {
+		return true
+	}
+
+	var activeURL, activeBranch, desiredURL, desiredBranch string
+	if active.Repo != nil {
+		activeURL, activeBranch = active.Repo.URL, active.Repo.SelectedBranch
+	}
+	if desired.Repo != nil {
+		desiredURL, desiredBranch = desired.Repo.URL, desired.Repo.SelectedBranch
+	}
+	if activeURL != desiredURL || activeBranch != desiredBranch {
+		return true
+	}
+
+	return !equalStrings(active.PeriodicSchedules, desired.PeriodicSchedules) ||
+		!equalStrings(active.Tags, desired.Tags)
+}
+
+// equalStrings checks if both slices contain the same strings in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // RenameBinary renames the binary file for the given pipeline.
 func RenameBinary(p gaia.Pipeline, newName string) error {
-	currentBinaryName := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
-	newBinaryName := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(newName, p.Type))
-	return os.Rename(currentBinaryName, newBinaryName)
+	currentBinaryName := GetExecPath(p)
+	renamed := p
+	renamed.Name = newName
+	newBinaryName := GetExecPath(renamed)
+	if err := os.Rename(currentBinaryName, newBinaryName); err != nil {
+		return err
+	}
+
+	// Move the source archive along if there is one
+	if err := os.Rename(currentBinaryName+sourceArchiveSuffix, newBinaryName+sourceArchiveSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Move the artifacts along if there are some
+	if err := os.Rename(currentBinaryName+artifactsSuffix, newBinaryName+artifactsSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Replace the metadata file if there is one
+	if err := os.Remove(currentBinaryName + binaryMetadataSuffix); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	p.Name = newName
+	return writeBinaryMetadata(newBinaryName, p)
 }
 
-// DeleteBinary deletes the binary for the given pipeline.
+// DeleteBinary deletes the binary, the metadata file, the source archive
+// and the artifacts for the given pipeline.
 func DeleteBinary(p gaia.Pipeline) error {
-	binaryFile := filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
+	binaryFile := GetExecPath(p)
+	if err := os.RemoveAll(binaryFile + artifactsSuffix); err != nil {
+		return err
+	}
+	for _, suffix := range []string{binaryMetadataSuffix, sourceArchiveSuffix} {
+		if err := os.Remove(binaryFile + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return os.Remove(binaryFile)
 }
 
 // GetExecPath returns the path to the executable for the given pipeline.
 func GetExecPath(p gaia.Pipeline) string {
-	return filepath.Join(gaia.Cfg.PipelinePath, appendTypeToName(p.Name, p.Type))
+	return filepath.Join(gaia.Cfg.PipelinePath, pipelineFileName(p))
+}
+
+// pipelineFileName returns the file name of the binary of the given pipeline
+// in the pipelines folder. WebAssembly binaries keep their extension and
+// go plugin modules get the plugin suffix.
+func pipelineFileName(p gaia.Pipeline) string {
+	name := appendTypeToName(p.Name, p.Type)
+	switch {
+	case p.PluginModule:
+		name += golangPluginSuffix
+	case isWasmBinary(p.ExecPath):
+		name += wasmExtension
+	}
+	return name
+}
+
+// binaryDestination returns the destination path inside the pipelines
+// folder for the build result of the given pipeline. Build results for a
+// specific target are stored in a separate folder so they are not picked
+// up as standalone pipelines. WebAssembly binaries run on every host and
+// stay in the pipelines folder unless they are an entry of a build matrix.
+// Go plugin modules get the plugin suffix.
+func binaryDestination(p *gaia.CreatePipeline) string {
+	name := buildOutputName(p)
+	if p.GoPlugin {
+		name += golangPluginSuffix
+	}
+	if p.MatrixEntry || (p.Target != nil && !isWasmTarget(p.Target.OS)) {
+		return filepath.Join(gaia.Cfg.PipelinePath, matrixFolder, name)
+	}
+	return filepath.Join(gaia.Cfg.PipelinePath, name)
 }
 
 // appendTypeToName appends the type to the output binary name.
 // This allows us later to define the pipeline type by the name.
+// Go binaries on windows hosts get the executable extension.
 func appendTypeToName(n string, pType gaia.PipelineType) string {
-	return fmt.Sprintf("%s%s%s", n, typeDelimiter, pType.String())
+	return appendTypeToNameForOS(n, pType, hostOS)
+}
+
+// appendTypeToNameForOS appends the type to the output binary name
+// and adds the executable extension required by the given operating system.
+func appendTypeToNameForOS(n string, pType gaia.PipelineType, goos string) string {
+	name := binaryName(n, pType)
+	if pType == gaia.PTypeGolang {
+		switch {
+		case goos == "windows":
+			name += windowsExecutableExtension
+		case isWasmTarget(goos):
+			name += wasmExtension
+		}
+	}
+	return name
+}
+
+// isWasmTarget checks if go builds for the given operating system
+// produce WebAssembly binaries.
+func isWasmTarget(goos string) bool {
+	return goos == "js" || goos == "wasip1"
+}
+
+// isWasmBinary checks if the given file is a WebAssembly binary.
+func isWasmBinary(path string) bool {
+	return strings.HasSuffix(path, wasmExtension)
+}
+
+// buildOutputName returns the file name of the build result of the given
+// pipeline. The name depends on the build target if one has been set.
+// TinyGo WebAssembly builds are named like go WebAssembly builds.
+func buildOutputName(p *gaia.CreatePipeline) string {
+	goos := hostOS
+	switch {
+	case p.Target != nil:
+		goos = p.Target.OS
+	case p.TinyGo && isTinyGoWasmTarget(p.TinyGoTarget):
+		goos = "wasip1"
+	}
+	return appendTypeToNameForOS(p.Pipeline.Name, p.Pipeline.Type, goos)
+}
+
+// buildPhase represents a single phase of the build process.
+// Every phase has its own timeout.
+type buildPhase string
+
+const (
+	// phasePrepare fetches all dependencies of the pipeline.
+	phasePrepare buildPhase = "prepare"
+
+	// phaseCompile compiles the pipeline.
+	phaseCompile buildPhase = "compile"
+
+	// phaseSmokeTest runs the built binary. It has the timeout of the pipeline.
+	phaseSmokeTest buildPhase = "smoketest"
+)
+
+// timeout returns the configured timeout of the build phase.
+func (b buildPhase) timeout() time.Duration {
+	switch b {
+	case phasePrepare:
+		if gaia.Cfg.BuildPrepareTimeout > 0 {
+			return gaia.Cfg.BuildPrepareTimeout
+		}
+		return defaultPrepareTimeout
+	default:
+		if gaia.Cfg.BuildCompileTimeout > 0 {
+			return gaia.Cfg.BuildCompileTimeout
+		}
+		return defaultCompileTimeout
+	}
 }
 
-// executeCmd wraps a context around the command and executes it.
-func executeCmd(path string, args []string, env []string, dir string) ([]byte, error) {
+// buildTimeoutError is returned when a build phase exceeded its timeout.
+type buildTimeoutError struct {
+	phase   buildPhase
+	timeout time.Duration
+}
+
+// Error implements the error interface.
+func (e *buildTimeoutError) Error() string {
+	return fmt.Sprintf("build phase %s timed out after %s", e.phase, e.timeout)
+}
+
+// Unwrap returns the underlying context error.
+func (e *buildTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// warnTimeout logs that the build phase of the pipeline with the given name
+// is about to time out. The warning is written to the build log as well.
+func warnTimeout(name string, log io.Writer, phase buildPhase, path string, timeout time.Duration) {
+	gaia.Cfg.Logger.Warn("build phase is about to time out", "pipeline", name, "phase", phase, "command", path, "timeout", timeout.String())
+	if log != nil {
+		_, _ = fmt.Fprintf(log, "\nwarning: build phase %s elapsed %d%% of its timeout of %s\n", phase, timeoutWarningPercent, timeout)
+	}
+}
+
+// executeCmd wraps a context with the timeout of the given build phase
+// around the command and executes it. If the timeout has been exceeded,
+// the timed out phase is appended to the output.
+func executeCmd(phase buildPhase, path string, args []string, env []string, dir string) ([]byte, error) {
+	return runCmd(context.Background(), "", nil, phase, path, args, env, dir)
+}
+
+// executeBuildCmd executes the command like executeCmd and additionally
+// writes the output to the build log of the given pipeline. The command
+// is killed if the build gets cancelled.
+func executeBuildCmd(p *gaia.CreatePipeline, phase buildPhase, path string, args []string, env []string, dir string) ([]byte, error) {
+	return executeBuildCmdWithInput(p, phase, nil, path, args, env, dir)
+}
+
+// executeBuildCmdWithInput executes the command like executeBuildCmd and
+// passes the given input on stdin. Secrets are passed this way because
+// the arguments of a process are visible to all users of the server.
+func executeBuildCmdWithInput(p *gaia.CreatePipeline, phase buildPhase, input []byte, path string, args []string, env []string, dir string) ([]byte, error) {
+	return runCmdWithTimeout(buildContext(p), p.Pipeline.Name, buildLogWriter(p.Pipeline.Name), phase, phase.timeout(), input, path, args, env, dir)
+}
+
+// runCmd executes the command until the parent context is done and writes
+// the output to log if it is not nil. A warning is logged for the pipeline
+// with the given name once the command is about to time out.
+func runCmd(parent context.Context, name string, log io.Writer, phase buildPhase, path string, args []string, env []string, dir string) ([]byte, error) {
+	return runCmdWithTimeout(parent, name, log, phase, phase.timeout(), nil, path, args, env, dir)
+}
+
+// runCmdWithTimeout executes the command like runCmd but with the given
+// timeout instead of the timeout of the build phase. The input is passed
+// on stdin if it is not nil.
+func runCmdWithTimeout(parent context.Context, name string, log io.Writer, phase buildPhase, timeout time.Duration, input []byte, path string, args []string, env []string, dir string) ([]byte, error) {
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), maxTimeoutMinutes*time.Minute)
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
+	// Warn before the command gets killed
+	warning := time.AfterFunc(timeout*timeoutWarningPercent/100, func() {
+		warnTimeout(name, log, phase, path, timeout)
+	})
+	defer warning.Stop()
+
 	// Create command
 	cmd := execCommandContext(ctx, path, args...)
 	cmd.Env = env
 	cmd.Dir = dir
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	output := new(bytes.Buffer)
+	var out io.Writer = output
+	if log != nil {
+		out = io.MultiWriter(output, log)
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
 
 	// Execute command
-	return cmd.CombinedOutput()
+	err := cmd.Run()
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = &buildTimeoutError{phase: phase, timeout: timeout}
+		_, _ = io.WriteString(out, "\n"+err.Error())
+	} else if err != nil && parent.Err() == context.Canceled {
+		err = errBuildCancelled
+		_, _ = io.WriteString(out, "\n"+err.Error())
+	}
+	return output.Bytes(), err
+}
+
+// installBinary copies the given build result to the destination inside
+// the pipelines folder and sets the execution rights.
+func installBinary(src, dest string) error {
+	// Check the destination folder first. Otherwise a missing pipelines
+	// folder surfaces as an opaque "no such file or directory" error.
+	destFolder := filepath.Dir(dest)
+	info, err := os.Stat(destFolder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("pipelines folder %s does not exist. Create it or check the configured home path", destFolder)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("pipelines folder %s is not a directory. Remove it or check the configured home path", destFolder)
+	}
+
+	// Copy binary. Oversized artifacts are rejected and removed before
+	// they fill up the pipelines folder.
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	maxSize := maxArtifactSize()
+	tooLarge := fmt.Errorf("artifact %s exceeds the maximum artifact size of %d bytes", filepath.Base(src), maxSize)
+	if info, err := in.Stat(); err == nil && info.Size() > maxSize {
+		_ = os.Remove(src)
+		return tooLarge
+	}
+	err = writeArtifact(dest, func(out io.Writer) error {
+		n, err := io.Copy(out, io.LimitReader(in, maxSize+1))
+		if err == nil && n > maxSize {
+			return tooLarge
+		}
+		return err
+	})
+	if err == tooLarge {
+		_ = os.Remove(src)
+	}
+	return err
+}
+
+// maxArtifactSize returns the configured maximum size of pipeline artifacts.
+func maxArtifactSize() int64 {
+	if gaia.Cfg.Artifact.MaxSize > 0 {
+		return gaia.Cfg.Artifact.MaxSize
+	}
+	return defaultMaxArtifactSize
+}
+
+// writeArtifact atomically writes a pipeline artifact to dest. The content
+// is written to a temporary file in the same folder which gets the
+// configured file mode and group before it is renamed to dest. Therefore,
+// dest never exists with the wrong permissions.
+func writeArtifact(dest string, write func(io.Writer) error) (err error) {
+	gid, err := artifactGroupID()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(artifactMode()); err != nil {
+		return err
+	}
+	if gid >= 0 {
+		if err = tmp.Chown(-1, gid); err != nil {
+			return fmt.Errorf("cannot change group of artifact: %s", err.Error())
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// artifactMode returns the configured file mode of pipeline artifacts.
+// By default, artifacts are only executable by the owner.
+func artifactMode() os.FileMode {
+	if gaia.Cfg.Artifact.Mode != 0 {
+		return gaia.Cfg.Artifact.Mode
+	}
+	return gaia.ExecutablePermission
+}
+
+// artifactGroupID returns the id of the configured group of pipeline
+// artifacts. The group can be given by name or id. -1 is returned if no
+// group has been configured.
+func artifactGroupID() (int, error) {
+	if gaia.Cfg.Artifact.Group == "" {
+		return -1, nil
+	}
+	if gid, err := strconv.Atoi(gaia.Cfg.Artifact.Group); err == nil {
+		return gid, nil
+	}
+	group, err := user.LookupGroup(gaia.Cfg.Artifact.Group)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(group.Gid)
 }
 
 // copyFileContents copies the content from source to destination.
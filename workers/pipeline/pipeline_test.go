@@ -1,13 +1,21 @@
 package pipeline
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
 )
 
 func TestAppend(t *testing.T) {
@@ -225,6 +233,77 @@ func TestReplaceByName(t *testing.T) {
 	}
 }
 
+func TestUpdateByName(t *testing.T) {
+	ap := NewActivePipelines()
+	ap.Append(gaia.Pipeline{Name: "Pipeline A", Type: gaia.PTypeGolang})
+	ap.Append(gaia.Pipeline{Name: "Pipeline B", Type: gaia.PTypeGolang})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ap.UpdateByName("Pipeline B", func(p *gaia.Pipeline) {
+				p.Tags = append(p.Tags, "tag")
+			})
+		}()
+	}
+	wg.Wait()
+
+	if p := ap.GetByName("Pipeline B"); len(p.Tags) != 10 {
+		t.Fatalf("expected 10 tags but got %d", len(p.Tags))
+	}
+	if p := ap.GetByName("Pipeline A"); len(p.Tags) != 0 {
+		t.Fatal("other pipelines should not be updated")
+	}
+	if ap.UpdateByName("Pipeline C", func(*gaia.Pipeline) {}) {
+		t.Fatal("expected no pipeline to be found")
+	}
+}
+
+func TestRename(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestRename")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.HomePath = tmp
+
+	ap := NewActivePipelines()
+	meta := &gaia.PipelineMetadata{Owner: "platform-team"}
+	ap.Append(gaia.Pipeline{Name: "Pipeline A", Type: gaia.PTypeGolang, Metadata: meta})
+	ap.Append(gaia.Pipeline{Name: "Pipeline B", Type: gaia.PTypeGolang})
+	binary := filepath.Join(tmp, appendTypeToName("Pipeline A", gaia.PTypeGolang))
+	_ = ioutil.WriteFile(binary, []byte("binary"), 0700)
+	BuildHistory.Add("Pipeline A", BuildRecord{Status: gaia.CreatePipelineSuccess})
+
+	if err := ap.Rename("Pipeline A", "Pipeline B"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing name error but got %v", err)
+	}
+	if err := ap.Rename("Pipeline C", "Pipeline D"); err == nil {
+		t.Fatal("expected error for unknown pipeline")
+	}
+
+	if err := ap.Rename("Pipeline A", "Pipeline C"); err != nil {
+		t.Fatal(err)
+	}
+	if ap.Contains("Pipeline A") {
+		t.Fatal("pipeline should not be found by the old name")
+	}
+	p := ap.GetByName("Pipeline C")
+	if p == nil || p.Metadata != meta || p.ExecPath != filepath.Join(tmp, appendTypeToName("Pipeline C", gaia.PTypeGolang)) {
+		t.Fatalf("unexpected renamed pipeline: %+v", p)
+	}
+	if content, err := ioutil.ReadFile(p.ExecPath); err != nil || string(content) != "binary" {
+		t.Fatalf("expected renamed binary. error: %v", err)
+	}
+	if h := GetHistory("Pipeline C", 0); len(h) != 1 {
+		t.Fatalf("expected build history to be moved but got %d records", len(h))
+	}
+	if h := GetHistory("Pipeline A", 0); len(h) != 0 {
+		t.Fatalf("expected no build history for the old name but got %d records", len(h))
+	}
+}
+
 func TestIter(t *testing.T) {
 	ap := NewActivePipelines()
 
@@ -273,6 +352,27 @@ func TestContains(t *testing.T) {
 	}
 }
 
+func TestAsMap(t *testing.T) {
+	ap := NewActivePipelines()
+	ap.Append(gaia.Pipeline{Name: "Pipeline A", Type: gaia.PTypeGolang})
+	ap.Append(gaia.Pipeline{Name: "Pipeline B", Type: gaia.PTypeJava})
+	ap.Append(gaia.Pipeline{Name: "Pipeline A", Type: gaia.PTypePython})
+
+	m := ap.AsMap()
+	if len(m) != 2 {
+		t.Fatalf("expected 2 pipelines in the map but got %d", len(m))
+	}
+	if m["Pipeline A"].Type != gaia.PTypeGolang || m["Pipeline B"].Type != gaia.PTypeJava {
+		t.Fatalf("unexpected pipelines in the map: %+v", m)
+	}
+
+	// The snapshot does not change with the active pipelines
+	ap.Append(gaia.Pipeline{Name: "Pipeline C"})
+	if _, ok := m["Pipeline C"]; ok {
+		t.Fatal("snapshot should not contain pipelines appended later")
+	}
+}
+
 func TestRemoveDeletedPipelines(t *testing.T) {
 	ap := NewActivePipelines()
 
@@ -310,6 +410,200 @@ func TestRemoveDeletedPipelines(t *testing.T) {
 
 }
 
+func TestReconcileWithDir(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestReconcileWithDir")
+	defer os.RemoveAll(tmp)
+	ap := NewActivePipelines()
+
+	// Pipeline A is active and still present on disk, Pipeline B vanished.
+	ap.Append(gaia.Pipeline{Name: "PipelineA", Type: gaia.PTypeGolang})
+	ap.Append(gaia.Pipeline{Name: "PipelineB", Type: gaia.PTypeGolang})
+	files := []string{
+		appendTypeToName("PipelineA", gaia.PTypeGolang),
+		appendTypeToName("PipelineC", gaia.PTypePython),
+		"README",
+	}
+	for _, f := range files {
+		_ = ioutil.WriteFile(filepath.Join(tmp, f), []byte("testcontent"), 0666)
+	}
+
+	added, removed, err := ap.ReconcileWithDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || added[0] != "PipelineC" {
+		t.Fatalf("expected PipelineC to be added. got: %v", added)
+	}
+	if len(removed) != 1 || removed[0] != "PipelineB" {
+		t.Fatalf("expected PipelineB to be removed. got: %v", removed)
+	}
+
+	p := ap.GetByName("PipelineC")
+	if p == nil {
+		t.Fatal("PipelineC should be part of the active pipelines")
+	}
+	if p.Type != gaia.PTypePython {
+		t.Fatalf("expected type %s. got %s", gaia.PTypePython, p.Type)
+	}
+	if ap.Contains("PipelineB") {
+		t.Fatal("PipelineB should have been removed")
+	}
+	if len(ap.GetAll()) != 2 {
+		t.Fatalf("expected 2 active pipelines. got %d", len(ap.GetAll()))
+	}
+}
+
+func TestReconcileWithDirMissingFolder(t *testing.T) {
+	ap := NewActivePipelines()
+	if _, _, err := ap.ReconcileWithDir("/noneexistent"); err == nil {
+		t.Fatal("expected error when folder does not exist")
+	}
+}
+
+func TestTotalBinarySize(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestTotalBinarySize")
+	defer os.RemoveAll(tmp)
+	ap := NewActivePipelines()
+
+	pA := filepath.Join(tmp, appendTypeToName("PipelineA", gaia.PTypeGolang))
+	pB := filepath.Join(tmp, appendTypeToName("PipelineB", gaia.PTypeGolang))
+	_ = ioutil.WriteFile(pA, []byte("1234"), 0666)
+	_ = ioutil.WriteFile(pB, []byte("123456"), 0666)
+	ap.Append(gaia.Pipeline{Name: "PipelineA", Type: gaia.PTypeGolang, ExecPath: pA})
+	ap.Append(gaia.Pipeline{Name: "PipelineB", Type: gaia.PTypeGolang, ExecPath: pB})
+
+	size, err := ap.TotalBinarySize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 10 {
+		t.Fatalf("expected total size of 10 but got %d", size)
+	}
+
+	// Missing binaries are reported but do not hide the total size
+	missing := filepath.Join(tmp, appendTypeToName("PipelineC", gaia.PTypeGolang))
+	ap.Append(gaia.Pipeline{Name: "PipelineC", Type: gaia.PTypeGolang, ExecPath: missing})
+	size, err = ap.TotalBinarySize()
+	if err == nil {
+		t.Fatal("expected error for missing binary")
+	}
+	if !strings.Contains(err.Error(), missing) {
+		t.Fatalf("error should contain the missing binary. was: %s", err.Error())
+	}
+	if size != 10 {
+		t.Fatalf("expected total size of 10 but got %d", size)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	ap := NewActivePipelines()
+	ap.Append(gaia.Pipeline{Name: "unchanged", Type: gaia.PTypeGolang, Repo: &gaia.GitRepo{URL: "url", SelectedBranch: "master"}, ID: 1})
+	ap.Append(gaia.Pipeline{Name: "branch", Type: gaia.PTypeGolang, Repo: &gaia.GitRepo{URL: "url", SelectedBranch: "master"}})
+	ap.Append(gaia.Pipeline{Name: "tags", Type: gaia.PTypeGolang, Tags: []string{"a"}})
+	ap.Append(gaia.Pipeline{Name: "removed", Type: gaia.PTypeGolang})
+
+	desired := []gaia.Pipeline{
+		// Generated fields like the ID do not count as a change
+		{Name: "unchanged", Type: gaia.PTypeGolang, Repo: &gaia.GitRepo{URL: "url", SelectedBranch: "master"}, ID: 2},
+		{Name: "branch", Type: gaia.PTypeGolang, Repo: &gaia.GitRepo{URL: "url", SelectedBranch: "develop"}},
+		{Name: "tags", Type: gaia.PTypeGolang, Tags: []string{"a", "b"}},
+		{Name: "added", Type: gaia.PTypePython},
+	}
+	toAdd, toUpdate, toRemove := ap.Diff(desired)
+	if len(toAdd) != 1 || toAdd[0].Name != "added" {
+		t.Fatalf("expected 'added' to be added but got %v", toAdd)
+	}
+	if len(toUpdate) != 2 || toUpdate[0].Name != "branch" || toUpdate[1].Name != "tags" {
+		t.Fatalf("expected 'branch' and 'tags' to be updated but got %v", toUpdate)
+	}
+	if len(toRemove) != 1 || toRemove[0].Name != "removed" {
+		t.Fatalf("expected 'removed' to be removed but got %v", toRemove)
+	}
+}
+
+func TestAppendWithResult(t *testing.T) {
+	ap := NewActivePipelines()
+	ap.Append(gaia.Pipeline{Name: "Pipeline A"})
+	i := ap.AppendWithResult(gaia.Pipeline{Name: "Pipeline B"})
+	if i != 1 {
+		t.Fatalf("expected index 1 but got %d", i)
+	}
+
+	p, ok := ap.GetByIndex(i)
+	if !ok || p.Name != "Pipeline B" {
+		t.Fatalf("expected Pipeline B at index %d but got %s", i, p.Name)
+	}
+	for _, invalid := range []int{-1, 2} {
+		if _, ok := ap.GetByIndex(invalid); ok {
+			t.Fatalf("expected index %d to be out of bounds", invalid)
+		}
+	}
+}
+
+func TestInstallBinaryMode(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestInstallBinaryMode")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Artifact.Mode = 0750
+	gaia.Cfg.Artifact.Group = strconv.Itoa(os.Getgid())
+
+	src := filepath.Join(tmp, "src")
+	_ = ioutil.WriteFile(src, []byte("binary"), 0600)
+	dest := filepath.Join(tmp, "pipelines", appendTypeToName("test", gaia.PTypeGolang))
+	_ = os.Mkdir(filepath.Dir(dest), 0700)
+	if err := installBinary(src, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Fatalf("expected mode 0750 but got %o", info.Mode().Perm())
+	}
+
+	// No temporary files must be left
+	files, _ := ioutil.ReadDir(filepath.Dir(dest))
+	if len(files) != 1 {
+		t.Fatalf("expected only the binary in the pipelines folder but got %d files", len(files))
+	}
+
+	// Unknown groups fail the install
+	gaia.Cfg.Artifact.Group = "gaia-unknown-group"
+	if err := installBinary(src, dest); err == nil {
+		t.Fatal("expected error for unknown group")
+	}
+}
+
+func TestInstallBinaryMaxSize(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestInstallBinaryMaxSize")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Artifact.MaxSize = 4
+
+	src := filepath.Join(tmp, "src")
+	dest := filepath.Join(tmp, "pipelines", appendTypeToName("test", gaia.PTypeGolang))
+	_ = os.Mkdir(filepath.Dir(dest), 0700)
+	_ = ioutil.WriteFile(src, []byte("bin"), 0600)
+	if err := installBinary(src, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	// Oversized artifacts are rejected and removed
+	_ = ioutil.WriteFile(src, []byte("binary"), 0600)
+	err := installBinary(src, dest)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum artifact size of 4 bytes") {
+		t.Fatalf("expected max size error but got: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatal("oversized artifact should have been removed")
+	}
+	if content, _ := ioutil.ReadFile(dest); string(content) != "bin" {
+		t.Fatalf("installed binary should not be replaced but got %s", content)
+	}
+}
+
 func TestRenameBinary(t *testing.T) {
 	tmp, _ := ioutil.TempDir("", "TestRenameBinary")
 	gaia.Cfg = new(gaia.Config)
@@ -428,3 +722,211 @@ func TestNewBuildPipeline(t *testing.T) {
 		t.Fatalf("should be of type %s but is nil\n", gaia.PTypeNodeJS)
 	}
 }
+
+type mockBuildPipeline struct {
+	BuildPipelineGolang
+}
+
+func TestRegisterBuildPipeline(t *testing.T) {
+	mockType := gaia.PipelineType("mock")
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+
+	if newBuildPipeline(mockType) != nil {
+		t.Fatal("unregistered pipeline type should not be supported")
+	}
+	if _, err := gaia.ParsePipelineType("mock"); err == nil {
+		t.Fatal("unregistered pipeline type should not be parsed")
+	}
+
+	RegisterBuildPipeline(mockType, func() BuildPipeline {
+		return new(mockBuildPipeline)
+	})
+	if _, ok := newBuildPipeline(mockType).(*mockBuildPipeline); !ok {
+		t.Fatal("expected registered mock build pipeline")
+	}
+	if pType, err := gaia.ParsePipelineType("mock"); err != nil || pType != mockType {
+		t.Fatalf("expected registered pipeline type to be parsed but got %s: %v", pType, err)
+	}
+}
+
+func TestExecuteCmdPhaseTimeout(t *testing.T) {
+	execCommandContext = fakeExecCommandContext
+	defer func() {
+		execCommandContext = exec.CommandContext
+	}()
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	gaia.Cfg.BuildPrepareTimeout = time.Nanosecond
+
+	if timeout := phaseCompile.timeout(); timeout != defaultCompileTimeout {
+		t.Fatalf("expected default compile timeout %s but got %s", defaultCompileTimeout, timeout)
+	}
+
+	output, err := executeCmd(phasePrepare, "go", []string{"mod", "download"}, os.Environ(), "")
+	var timeoutErr *buildTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.phase != phasePrepare {
+		t.Fatalf("expected timeout of the prepare phase but got: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("timeout error should wrap the context deadline error")
+	}
+	if !strings.Contains(string(output), "build phase prepare timed out") {
+		t.Fatalf("expected timed out phase in output but got: %s", string(output))
+	}
+}
+
+func TestRunCmdTimeoutWarning(t *testing.T) {
+	buf := new(bytes.Buffer)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{Output: buf})
+	gaia.Cfg.BuildCompileTimeout = 500 * time.Millisecond
+
+	// The warning is written to the build log while the command runs
+	log, _ := ioutil.TempFile("", "TestRunCmdTimeoutWarning")
+	defer os.Remove(log.Name())
+	defer log.Close()
+	_, err := runCmd(context.Background(), "mypipeline", log, phaseCompile, "/bin/sleep", []string{"1"}, nil, "")
+	var timeoutErr *buildTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected timeout but got: %v", err)
+	}
+	if !strings.Contains(buf.String(), "build phase is about to time out") || !strings.Contains(buf.String(), "pipeline=mypipeline") {
+		t.Fatalf("expected timeout warning in log but got: %s", buf.String())
+	}
+	content, _ := ioutil.ReadFile(log.Name())
+	if !strings.Contains(string(content), "warning: build phase compile elapsed 80% of its timeout of 500ms") {
+		t.Fatalf("expected timeout warning in build log but got: %s", string(content))
+	}
+
+	// Fast commands are not warned about
+	buf.Reset()
+	gaia.Cfg.BuildCompileTimeout = time.Minute
+	if _, err := runCmd(context.Background(), "mypipeline", nil, phaseCompile, "/bin/true", nil, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "about to time out") {
+		t.Fatalf("expected no timeout warning but got: %s", buf.String())
+	}
+}
+
+type missingToolchainBuildPipeline struct {
+	mockBuildPipeline
+}
+
+func (b *missingToolchainBuildPipeline) CheckEnvironment() error {
+	return checkToolchain("Rust", "gaia-nonexistent-cargo")
+}
+
+func TestCanBuild(t *testing.T) {
+	mockType := gaia.PipelineType("mock")
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+
+	if ok, err := CanBuild(mockType); ok || err == nil {
+		t.Fatal("unregistered pipeline type should not be buildable")
+	}
+
+	RegisterBuildPipeline(mockType, func() BuildPipeline {
+		return new(missingToolchainBuildPipeline)
+	})
+	ok, err := CanBuild(mockType)
+	if ok || err == nil || !strings.Contains(err.Error(), "Rust toolchain not installed") {
+		t.Fatalf("expected missing toolchain error but got: %v", err)
+	}
+}
+
+func TestCanBuildImage(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCanBuildImage")
+	defer os.RemoveAll(tmp)
+	mockType := gaia.PipelineType("mock")
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+	RegisterBuildPipeline(mockType, func() BuildPipeline {
+		return new(missingToolchainBuildPipeline)
+	})
+
+	// The toolchain of the type is not required but the container builder is
+	defer fakeToolchain(t, tmp)()
+	if ok, err := CanBuildImage(mockType); ok || err != errMissingContainerBuilder {
+		t.Fatalf("expected error '%v' but got '%v'", errMissingContainerBuilder, err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(tmp, "buildah"), nil, 0700)
+	if ok, err := CanBuildImage(mockType); !ok || err != nil {
+		t.Fatalf("expected image to be buildable but got: %v", err)
+	}
+	if ok, _ := CanBuildImage(gaia.PipelineType("unknown")); ok {
+		t.Fatal("unregistered pipeline type should not be buildable")
+	}
+}
+
+func TestValidateAllBuildEnvironments(t *testing.T) {
+	mockType := gaia.PipelineType("mock")
+	defer func() {
+		buildPipelineFactoriesLock.Lock()
+		delete(buildPipelineFactories, mockType)
+		buildPipelineFactoriesLock.Unlock()
+	}()
+	RegisterBuildPipeline(mockType, func() BuildPipeline {
+		return new(missingToolchainBuildPipeline)
+	})
+
+	report := ValidateAllBuildEnvironments()
+	for _, pType := range []gaia.PipelineType{gaia.PTypeGolang, gaia.PTypeJava, gaia.PTypePython, gaia.PTypeCpp, gaia.PTypeRuby, gaia.PTypeNodeJS, gaia.PTypePerl, mockType} {
+		if _, ok := report[pType]; !ok {
+			t.Fatalf("expected pipeline type %s in report", pType)
+		}
+	}
+	if err := report[mockType]; err == nil || !strings.Contains(err.Error(), "Rust toolchain not installed") {
+		t.Fatalf("expected missing toolchain error but got: %v", err)
+	}
+	if err := report[gaia.PTypeGolang]; err != nil {
+		t.Fatalf("expected go toolchain to be installed but got: %v", err)
+	}
+}
+
+func TestBuildPipelineNilCreatePipeline(t *testing.T) {
+	buildPipelines := []BuildPipeline{&BuildPipelineOCI{Type: new(BuildPipelineGolang)}}
+	for _, pType := range []gaia.PipelineType{gaia.PTypeGolang, gaia.PTypeJava, gaia.PTypePython, gaia.PTypeCpp, gaia.PTypeRuby, gaia.PTypeNodeJS, gaia.PTypePerl} {
+		buildPipelines = append(buildPipelines, newBuildPipeline(pType))
+	}
+
+	for _, bP := range buildPipelines {
+		if err := bP.PrepareEnvironment(nil); err != errNilCreatePipeline {
+			t.Fatalf("%T: expected error '%v' from PrepareEnvironment but got '%v'", bP, errNilCreatePipeline, err)
+		}
+		if err := bP.ExecuteBuild(nil); err != errNilCreatePipeline {
+			t.Fatalf("%T: expected error '%v' from ExecuteBuild but got '%v'", bP, errNilCreatePipeline, err)
+		}
+		if err := bP.CopyBinary(nil); err != errNilCreatePipeline {
+			t.Fatalf("%T: expected error '%v' from CopyBinary but got '%v'", bP, errNilCreatePipeline, err)
+		}
+	}
+}
+
+func TestGetGlobalActivePipelinesConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	results := make([]*ActivePipelines, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = GetGlobalActivePipelines()
+		}(i)
+	}
+	wg.Wait()
+	for _, ap := range results {
+		if ap == nil || ap != results[0] {
+			t.Fatal("expected all callers to get the same active pipelines")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	httpProxyEnv  = "HTTP_PROXY"
+	httpsProxyEnv = "HTTPS_PROXY"
+	noProxyEnv    = "NO_PROXY"
+)
+
+// proxyEnvKeys are the names of all proxy variables. Tools differ in whether
+// they read the upper or the lower case variant, so both are used.
+var proxyEnvKeys = []string{
+	httpProxyEnv, strings.ToLower(httpProxyEnv),
+	httpsProxyEnv, strings.ToLower(httpsProxyEnv),
+	noProxyEnv, strings.ToLower(noProxyEnv),
+}
+
+// proxyEnvironment applies the configured build proxy to the given
+// environment. Configured values replace the inherited variables in both
+// cases. Without configuration the proxy of the server process is inherited.
+func proxyEnvironment(env []string) []string {
+	configured := map[string]string{
+		httpProxyEnv:  gaia.Cfg.BuildProxy.HTTP,
+		httpsProxyEnv: gaia.Cfg.BuildProxy.HTTPS,
+		noProxyEnv:    gaia.Cfg.BuildProxy.NoProxy,
+	}
+
+	result := make([]string, 0, len(env))
+	for _, v := range env {
+		key := strings.SplitN(v, "=", 2)[0]
+		if configured[strings.ToUpper(key)] != "" && isProxyEnvKey(key) {
+			continue
+		}
+		result = append(result, v)
+	}
+	for _, key := range []string{httpProxyEnv, httpsProxyEnv, noProxyEnv} {
+		if value := configured[key]; value != "" {
+			result = append(result, key+"="+value, strings.ToLower(key)+"="+value)
+		}
+	}
+	return result
+}
+
+// isProxyEnvKey checks if the given variable name is a proxy variable.
+func isProxyEnvKey(key string) bool {
+	for _, k := range proxyEnvKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyBuildArgs returns the build arguments which pass the proxy variables
+// of the given environment into a container image build. The values are
+// taken from the environment of the builder.
+func proxyBuildArgs(env []string) []string {
+	var args []string
+	seen := map[string]bool{}
+	for _, v := range env {
+		key := strings.SplitN(v, "=", 2)[0]
+		if isProxyEnvKey(key) && !seen[key] {
+			seen[key] = true
+			args = append(args, "--build-arg", key)
+		}
+	}
+	return args
+}
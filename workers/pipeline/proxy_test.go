@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestProxyEnvironment(t *testing.T) {
+	gaia.Cfg = new(gaia.Config)
+	env := []string{"PATH=/bin", "HTTP_PROXY=http://server:3128", "no_proxy=localhost"}
+
+	// Without configuration the proxy of the server is inherited
+	if got := proxyEnvironment(env); !equalStrings(got, env) {
+		t.Fatalf("expected inherited environment %v but got %v", env, got)
+	}
+
+	// Configured values replace both cases
+	gaia.Cfg.BuildProxy.HTTP = "http://build:3128"
+	gaia.Cfg.BuildProxy.NoProxy = "goproxy.internal"
+	got := proxyEnvironment(env)
+	expected := []string{
+		"PATH=/bin",
+		"HTTP_PROXY=http://build:3128", "http_proxy=http://build:3128",
+		"NO_PROXY=goproxy.internal", "no_proxy=goproxy.internal",
+	}
+	if !equalStrings(got, expected) {
+		t.Fatalf("expected environment %v but got %v", expected, got)
+	}
+}
+
+func TestProxyBuildArgs(t *testing.T) {
+	env := []string{"PATH=/bin", "HTTPS_PROXY=http://proxy:3128", "https_proxy=http://proxy:3128", "HTTPS_PROXY=http://other:3128"}
+	expected := "--build-arg,HTTPS_PROXY,--build-arg,https_proxy"
+	if got := strings.Join(proxyBuildArgs(env), ","); got != expected {
+		t.Fatalf("expected build args '%s' but got '%s'", expected, got)
+	}
+	if args := proxyBuildArgs([]string{"PATH=/bin"}); len(args) != 0 {
+		t.Fatalf("expected no build args but got %v", args)
+	}
+}
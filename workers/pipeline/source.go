@@ -0,0 +1,183 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// replaceFolder is the folder inside of the build context where the
+// replacements of modules are copied to.
+const replaceFolder = ".gaia/replace"
+
+// replaceInvalidChars matches all characters which are not allowed
+// in the folder name of a replacement.
+var replaceInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+// errSourceRootNotSet is thrown when a local source path is used but
+// no source root has been configured.
+var errSourceRootNotSet = errors.New("local source paths are disabled. configure the source root of the server to enable them")
+
+// acquireSource fetches the sources of the given pipeline into the
+// local destination of the repository. If a local source path has been
+// defined, the sources are copied from there. Otherwise the repository
+// is fetched from the version control system of the pipeline.
+func acquireSource(p *gaia.CreatePipeline) error {
+	if p.SourcePath != "" {
+		src, err := resolveSourcePath(p.SourcePath)
+		if err != nil {
+			return err
+		}
+		if err := copySource(src, p.Pipeline.Repo.LocalDest); err != nil {
+			return err
+		}
+		return copyReplacements(p)
+	}
+
+	vcs, err := newVCS(p.VCS)
+	if err != nil {
+		return err
+	}
+	if p.RequireCleanTree {
+		if _, ok := vcs.(gitVCS); !ok {
+			return fmt.Errorf("clean working tree check is not supported for %s", p.VCS)
+		}
+	}
+	if err := vcs.Fetch(p); err != nil {
+		return err
+	}
+	if p.RequireCleanTree {
+		if err := checkCleanWorktree(p.Pipeline.Repo.LocalDest); err != nil {
+			return err
+		}
+	}
+	return copyReplacements(p)
+}
+
+// copyReplacements copies the local directories of all replaced modules
+// into the build context. Shared packages which live outside of the
+// repository are part of the build this way. The directories must be
+// located inside of the configured source root.
+func copyReplacements(p *gaia.CreatePipeline) error {
+	for _, module := range replacedModules(p) {
+		src, err := resolveSourcePath(p.Replace[module])
+		if err != nil {
+			return fmt.Errorf("cannot copy replacement of module %s: %s", module, err.Error())
+		}
+		dest := filepath.Join(p.Pipeline.Repo.LocalDest, replacementPath(module))
+		if err := os.MkdirAll(dest, 0700); err != nil {
+			return err
+		}
+		if err := copySource(src, dest); err != nil {
+			return fmt.Errorf("cannot copy replacement of module %s: %s", module, err.Error())
+		}
+	}
+	return nil
+}
+
+// replacedModules returns the sorted paths of all replaced modules.
+func replacedModules(p *gaia.CreatePipeline) []string {
+	modules := make([]string, 0, len(p.Replace))
+	for module := range p.Replace {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// replacementPath returns the path of the replacement of the given module
+// relative to the root of the build context.
+func replacementPath(module string) string {
+	return filepath.Join(replaceFolder, replaceInvalidChars.ReplaceAllString(module, "_"))
+}
+
+// resolveSourcePath resolves the given local source path against the
+// configured source root. Relative paths are relative to the source root.
+// An error is returned if the resolved path is located outside of it.
+func resolveSourcePath(path string) (string, error) {
+	if gaia.Cfg.SourceRoot == "" {
+		return "", errSourceRootNotSet
+	}
+	root, err := filepath.EvalSymlinks(gaia.Cfg.SourceRoot)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve source root: %s", err.Error())
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve source path %s: %s", path, err.Error())
+	}
+	if !withinDir(root, resolved) {
+		return "", fmt.Errorf("source path %s is located outside of the source root", path)
+	}
+	return resolved, nil
+}
+
+// withinDir returns true if the given path is dir or located inside of it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copySource copies the source tree from src to dest.
+// If src is a symlink, it is resolved first so that the build never
+// operates in place on the real source tree. Symlinks inside the tree
+// must point into the tree and are copied as relative symlinks.
+func copySource(src, dest string) error {
+	resolved, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return fmt.Errorf("cannot resolve source path %s: %s", src, err.Error())
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("source path %s is not a directory", src)
+	}
+
+	return filepath.Walk(resolved, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(resolved, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			return os.MkdirAll(target, mode.Perm()|0700)
+		case mode&os.ModeSymlink != 0:
+			linked, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return fmt.Errorf("cannot resolve symlink %s: %s", rel, err.Error())
+			}
+			if !withinDir(resolved, linked) {
+				return fmt.Errorf("symlink %s points outside of the source tree", rel)
+			}
+			link, err := filepath.Rel(filepath.Dir(path), linked)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case mode.IsRegular():
+			if err := copyFileContents(path, target); err != nil {
+				return err
+			}
+			return os.Chmod(target, mode.Perm())
+		default:
+			// Skip devices, sockets and named pipes
+			return nil
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// sourceArchiveSuffix is the suffix of the source archive which is
+// stored next to the pipeline binary.
+const sourceArchiveSuffix = ".src.tar.gz"
+
+// sourceArchiveExcludes are folders which are not part of the source archive
+// because they contain version control data or dependency caches.
+var sourceArchiveExcludes = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+	"__pycache__":  true,
+	".cache":       true,
+}
+
+// isSourceArchive checks if the given file name is a source archive.
+func isSourceArchive(fileName string) bool {
+	return strings.HasSuffix(fileName, sourceArchiveSuffix)
+}
+
+// archiveSource writes the source tree in the given folder into a
+// temporary tar.gz archive and returns the path to it.
+func archiveSource(root string) (path string, err error) {
+	tmpFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder)
+	if err = os.MkdirAll(tmpFolder, 0700); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(tmpFolder, "source")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if info.IsDir() && sourceArchiveExcludes[info.Name()] {
+			return filepath.SkipDir
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = tw.Close(); err != nil {
+		return "", err
+	}
+	if err = gw.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// archivedFiles returns the names of all entries in the given source archive.
+func archivedFiles(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestArchiveSource(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestArchiveSource")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+
+	src := filepath.Join(tmp, "src")
+	for _, file := range []string{"main.go", "pkg/lib.go", ".git/HEAD", "node_modules/dep/index.js"} {
+		path := filepath.Join(src, file)
+		_ = os.MkdirAll(filepath.Dir(path), 0700)
+		if err := ioutil.WriteFile(path, []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, err := archiveSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(archive)
+
+	names := archivedFiles(t, archive)
+	expected := []string{"main.go", "pkg", "pkg/lib.go"}
+	if !equalStrings(names, expected) {
+		t.Fatalf("expected archive entries %v but got %v", expected, names)
+	}
+}
+
+func TestIsSourceArchive(t *testing.T) {
+	if !isSourceArchive("pipeline_golang" + sourceArchiveSuffix) {
+		t.Fatal("expected source archive to be detected")
+	}
+	if isSourceArchive("pipeline_golang") {
+		t.Fatal("binary detected as source archive")
+	}
+}
+
+func TestRenameAndDeleteBinarySourceArchive(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestRenameAndDeleteBinarySourceArchive")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.PipelinePath = tmp
+	gaia.Cfg.HomePath = tmp
+
+	p := gaia.Pipeline{
+		Name:    "PipelineA",
+		Type:    gaia.PTypeGolang,
+		Created: time.Now(),
+	}
+	binary := filepath.Join(tmp, appendTypeToName(p.Name, p.Type))
+	_ = ioutil.WriteFile(binary, []byte("binary"), 0600)
+	_ = ioutil.WriteFile(binary+sourceArchiveSuffix, []byte("archive"), 0600)
+
+	if err := RenameBinary(p, "PipelineB"); err != nil {
+		t.Fatal(err)
+	}
+	p.Name = "PipelineB"
+	renamed := filepath.Join(tmp, appendTypeToName(p.Name, p.Type)) + sourceArchiveSuffix
+	if _, err := os.Stat(renamed); err != nil {
+		t.Fatalf("expected source archive to be renamed: %s", err)
+	}
+
+	if err := DeleteBinary(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(renamed); !os.IsNotExist(err) {
+		t.Fatal("expected source archive to be deleted")
+	}
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestCopySourceSymlink(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCopySourceSymlink")
+	defer os.RemoveAll(tmp)
+
+	realSource := filepath.Join(tmp, "source")
+	_ = os.MkdirAll(filepath.Join(realSource, "pkg"), 0700)
+	_ = ioutil.WriteFile(filepath.Join(realSource, "pkg", "main.go"), []byte("package main"), 0600)
+	_ = os.Symlink(filepath.Join(realSource, "pkg"), filepath.Join(realSource, "link"))
+	linkedSource := filepath.Join(tmp, "linked")
+	_ = os.Symlink(realSource, linkedSource)
+
+	dest := filepath.Join(tmp, "dest")
+	if err := copySource(linkedSource, dest); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dest, "pkg", "main.go"))
+	if err != nil || string(content) != "package main" {
+		t.Fatalf("expected source file to be copied. error: %v", err)
+	}
+	info, err := os.Lstat(filepath.Join(dest, "link"))
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected symlink to be copied as symlink. error: %v", err)
+	}
+	if link, _ := os.Readlink(filepath.Join(dest, "link")); link != "pkg" {
+		t.Fatalf("expected symlink to point into the copy but got '%s'", link)
+	}
+	if info, _ := os.Lstat(dest); info.Mode()&os.ModeSymlink != 0 {
+		t.Fatal("destination should be a copy and not a symlink")
+	}
+
+	// Symlinks must not point outside of the source tree
+	secret := filepath.Join(tmp, "secret")
+	_ = ioutil.WriteFile(secret, []byte("secret"), 0600)
+	_ = os.Symlink(secret, filepath.Join(realSource, "secret"))
+	if err := copySource(realSource, filepath.Join(tmp, "dest2")); err == nil || !strings.Contains(err.Error(), "outside of the source tree") {
+		t.Fatalf("expected error for symlink outside of the source tree but got: %v", err)
+	}
+}
+
+func TestResolveSourcePath(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestResolveSourcePath")
+	defer os.RemoveAll(tmp)
+	root := filepath.Join(tmp, "root")
+	_ = os.MkdirAll(filepath.Join(root, "pipeline"), 0700)
+	_ = os.Symlink(tmp, filepath.Join(root, "escape"))
+	gaia.Cfg = new(gaia.Config)
+
+	if _, err := resolveSourcePath("pipeline"); err != errSourceRootNotSet {
+		t.Fatalf("expected error '%v' but got '%v'", errSourceRootNotSet, err)
+	}
+
+	gaia.Cfg.SourceRoot = root
+	resolvedRoot, _ := filepath.EvalSymlinks(root)
+	for _, path := range []string{"pipeline", filepath.Join(root, "pipeline")} {
+		resolved, err := resolveSourcePath(path)
+		if err != nil || resolved != filepath.Join(resolvedRoot, "pipeline") {
+			t.Fatalf("expected %s to be resolved inside of the source root but got '%s': %v", path, resolved, err)
+		}
+	}
+	for _, path := range []string{"..", "pipeline/../..", tmp, "escape"} {
+		if _, err := resolveSourcePath(path); err == nil {
+			t.Fatalf("expected error for source path %s outside of the source root", path)
+		}
+	}
+}
+
+func TestAcquireSourceReplacements(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestAcquireSourceReplacements")
+	defer os.RemoveAll(tmp)
+
+	src := filepath.Join(tmp, "pipeline")
+	helper := filepath.Join(tmp, "helper")
+	_ = os.MkdirAll(src, 0700)
+	_ = os.MkdirAll(helper, 0700)
+	_ = ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(helper, "helper.go"), []byte("package helper"), 0600)
+
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.SourceRoot = tmp
+	p := &gaia.CreatePipeline{
+		SourcePath: src,
+		Replace:    map[string]string{"example.com/shared/helper": helper},
+	}
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: filepath.Join(tmp, "dest")}
+	if err := acquireSource(p); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(tmp, "dest", ".gaia", "replace", "example_com_shared_helper", "helper.go")
+	if _, err := os.Stat(expected); err != nil {
+		t.Fatalf("expected replacement to be copied into the build context: %v", err)
+	}
+
+	// Missing replacements fail the build
+	p.Replace["example.com/missing"] = filepath.Join(tmp, "missing")
+	p.Pipeline.Repo.LocalDest = filepath.Join(tmp, "dest2")
+	if err := acquireSource(p); err == nil || !strings.Contains(err.Error(), "example.com/missing") {
+		t.Fatalf("expected error for missing replacement but got: %v", err)
+	}
+
+	// Replacements must be located inside of the source root
+	delete(p.Replace, "example.com/missing")
+	gaia.Cfg.SourceRoot = src
+	p.SourcePath = "."
+	p.Pipeline.Repo.LocalDest = filepath.Join(tmp, "dest3")
+	if err := acquireSource(p); err == nil || !strings.Contains(err.Error(), "outside of the source root") {
+		t.Fatalf("expected error for replacement outside of the source root but got: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// SourceTransformer modifies the sources of a pipeline in the given folder
+// before they are compiled, e.g. to substitute environment specific values.
+// A returned error aborts the build.
+type SourceTransformer func(dir string, p gaia.Pipeline) error
+
+var (
+	// sourceTransformers are all registered source transformers.
+	sourceTransformers []SourceTransformer
+
+	// sourceTransformersLock protects the registered source transformers.
+	sourceTransformersLock sync.RWMutex
+)
+
+// RegisterSourceTransformer registers a source transformer which runs for
+// every build after the sources have been acquired. Transformers run in
+// the order they have been registered.
+func RegisterSourceTransformer(t SourceTransformer) {
+	sourceTransformersLock.Lock()
+	defer sourceTransformersLock.Unlock()
+
+	sourceTransformers = append(sourceTransformers, t)
+}
+
+// transformSource runs all registered source transformers on the sources
+// of the given pipeline. The first failed transformer aborts.
+func transformSource(p *gaia.CreatePipeline) error {
+	sourceTransformersLock.RLock()
+	defer sourceTransformersLock.RUnlock()
+
+	for i, transform := range sourceTransformers {
+		if err := transform(p.Pipeline.Repo.LocalDest, p.Pipeline); err != nil {
+			return fmt.Errorf("source transformer %d failed: %s", i+1, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestTransformSource(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestTransformSource")
+	defer os.RemoveAll(tmp)
+	defer func() { sourceTransformers = nil }()
+	_ = ioutil.WriteFile(filepath.Join(tmp, "config.go"), []byte("const env = \"{{ENV}}\""), 0600)
+
+	var calls []string
+	RegisterSourceTransformer(func(dir string, p gaia.Pipeline) error {
+		calls = append(calls, "first")
+		content, err := ioutil.ReadFile(filepath.Join(dir, "config.go"))
+		if err != nil {
+			return err
+		}
+		content = []byte(strings.Replace(string(content), "{{ENV}}", p.Name, 1))
+		return ioutil.WriteFile(filepath.Join(dir, "config.go"), content, 0600)
+	})
+	RegisterSourceTransformer(func(dir string, p gaia.Pipeline) error {
+		calls = append(calls, "second")
+		return nil
+	})
+
+	p := new(gaia.CreatePipeline)
+	p.Pipeline.Name = "production"
+	p.Pipeline.Repo = &gaia.GitRepo{LocalDest: tmp}
+	if err := transformSource(p); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(filepath.Join(tmp, "config.go")); string(content) != "const env = \"production\"" {
+		t.Fatalf("expected transformed source but got %s", string(content))
+	}
+	if strings.Join(calls, ",") != "first,second" {
+		t.Fatalf("expected transformers in registration order but got %v", calls)
+	}
+
+	// A failed transformer aborts the remaining ones
+	calls = nil
+	sourceTransformers = []SourceTransformer{
+		func(dir string, p gaia.Pipeline) error { return errors.New("missing value") },
+		func(dir string, p gaia.Pipeline) error { calls = append(calls, "skipped"); return nil },
+	}
+	if err := transformSource(p); err == nil || err.Error() != "source transformer 1 failed: missing value" {
+		t.Fatalf("expected transformer error but got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("expected remaining transformers to be skipped but got %v", calls)
+	}
+}
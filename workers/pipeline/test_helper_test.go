@@ -36,7 +36,7 @@ func TestExecCommandContextHelper(t *testing.T) {
 	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
 		return
 	}
-	_, _ = fmt.Fprintf(os.Stdout, os.Getenv("STDOUT"))
+	_, _ = fmt.Fprint(os.Stdout, os.Getenv("STDOUT"))
 	i, _ := strconv.Atoi(os.Getenv("EXIT_STATUS"))
 	os.Exit(i)
 }
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/gaia-pipeline/gaia/helper/filehelper"
-	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/services"
@@ -71,8 +70,9 @@ func StartPoller() error {
 // InitTicker initiates the pipeline ticker.
 // This periodic job will check for new pipelines.
 func InitTicker() {
-	// Init global active pipelines slice
-	GlobalActivePipelines = NewActivePipelines()
+	// Init global active pipelines slice. Requests which arrived
+	// before already share the same instance.
+	GetGlobalActivePipelines()
 
 	// Check immediately to make sure we fill the list as fast as possible.
 	checkActivePipelines()
@@ -106,25 +106,26 @@ func checkActivePipelines() {
 	} else {
 		// Iterate all found pipelines
 		for _, file := range files {
+			// Folders, e.g. the build matrix folder, and metadata files are not pipelines
+			if file.IsDir() || isBinaryMetadata(file.Name()) || isSourceArchive(file.Name()) {
+				continue
+			}
 			n := strings.TrimSpace(file.Name())
 
-			// Get pipeline type
-			pType, err := getPipelineType(n)
+			// Get real pipeline name and type and check if the global active
+			// pipelines slice already contains it.
+			pName, pType, err := parseBinaryName(gaia.Cfg.PipelinePath, n)
 			if err != nil {
 				gaia.Cfg.Logger.Debug("at least one pipeline in pipeline folder is missing the type definition")
 				gaia.Cfg.Logger.Debug("Info", "name", n)
 				gaia.Cfg.Logger.Error("error thrown", "error", err.Error())
 				continue
 			}
-
-			// Get real pipeline name and check if the global active pipelines slice
-			// already contains it.
-			pName := pipelinehelper.GetRealPipelineName(n, pType)
 			// Add the real pipeline name to the slice of existing pipeline names.
 			existingPipelineNames = append(existingPipelineNames, pName)
-			if GlobalActivePipelines.Contains(pName) {
+			if GetGlobalActivePipelines().Contains(pName) {
 				// If SHA256Sum is set, we should check if pipeline has been changed.
-				p := GlobalActivePipelines.GetByName(pName)
+				p := GetGlobalActivePipelines().GetByName(pName)
 				if p != nil && p.SHA256Sum != nil {
 					// Get SHA256 Checksum
 					checksum, err := filehelper.GetSHA256Sum(filepath.Join(gaia.Cfg.PipelinePath, file.Name()))
@@ -142,14 +143,17 @@ func checkActivePipelines() {
 							continue
 						}
 
-						// Let us try again to start the plugin and receive all implemented jobs
-						if err = schedulerService.SetPipelineJobs(p); err != nil {
-							// Mark that this pipeline is broken.
-							p.IsNotValid = true
+						// Let us try again to start the plugin and receive all implemented jobs.
+						// Plugin modules are loaded in-process and cannot be started.
+						if !p.PluginModule {
+							if err = schedulerService.SetPipelineJobs(p); err != nil {
+								// Mark that this pipeline is broken.
+								p.IsNotValid = true
+							}
 						}
 
 						// Replace pipeline
-						if ok := GlobalActivePipelines.Replace(*p); !ok {
+						if ok := GetGlobalActivePipelines().Replace(*p); !ok {
 							gaia.Cfg.Logger.Debug("cannot replace pipeline in global pipeline list", "pipeline", p)
 						}
 					}
@@ -176,6 +180,8 @@ func checkActivePipelines() {
 					ExecPath: filepath.Join(gaia.Cfg.PipelinePath, file.Name()),
 					Created:  time.Now(),
 					Tags:     []string{pType.String()},
+
+					PluginModule: isPluginModule(gaia.Cfg.PipelinePath, file.Name()),
 				}
 				shouldStore = true
 			}
@@ -198,11 +204,14 @@ func checkActivePipelines() {
 				_ = storeService.PipelinePut(pipeline)
 			}
 
-			// Let us try to start the plugin and receive all implemented jobs
-			if err = schedulerService.SetPipelineJobs(pipeline); err != nil {
-				// Mark that this pipeline is broken.
-				pipeline.IsNotValid = true
-				gaia.Cfg.Logger.Error("cannot get pipeline jobs", "error", err.Error(), "pipeline", pipeline)
+			// Let us try to start the plugin and receive all implemented jobs.
+			// Plugin modules are loaded in-process and cannot be started.
+			if !pipeline.PluginModule {
+				if err = schedulerService.SetPipelineJobs(pipeline); err != nil {
+					// Mark that this pipeline is broken.
+					pipeline.IsNotValid = true
+					gaia.Cfg.Logger.Error("cannot get pipeline jobs", "error", err.Error(), "pipeline", pipeline)
+				}
 			}
 
 			// Set up periodic schedules of this pipeline.
@@ -245,10 +254,10 @@ func checkActivePipelines() {
 			// to store and should not have any side effects.
 
 			// Append new pipeline
-			GlobalActivePipelines.Append(*pipeline)
+			GetGlobalActivePipelines().Append(*pipeline)
 		}
 	}
-	GlobalActivePipelines.RemoveDeletedPipelines(existingPipelineNames)
+	GetGlobalActivePipelines().RemoveDeletedPipelines(existingPipelineNames)
 }
 
 // updateWorker checks the latest worker information and determines the status
@@ -293,31 +302,26 @@ func updateWorker() {
 }
 
 // getPipelineType looks up for specific suffix on the given file name.
-// If found, returns the pipeline type.
+// The executable extension of windows binaries, the WebAssembly extension
+// and the suffix of go plugin modules are ignored.
+// If found, returns the pipeline type. File names without a pipeline
+// name in front of the type are rejected.
 func getPipelineType(n string) (gaia.PipelineType, error) {
-	s := strings.Split(n, typeDelimiter)
+	n = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(n, windowsExecutableExtension), wasmExtension), golangPluginSuffix)
+	i := strings.LastIndex(n, typeDelimiter)
 
-	// Length must be higher than one
-	if len(s) < 2 {
+	// The delimiter must be present
+	if i < 0 {
 		return gaia.PTypeUnknown, errMissingType
 	}
+	if i == 0 {
+		return gaia.PTypeUnknown, errMissingName
+	}
 
 	// Get last element and look for type
-	t := s[len(s)-1]
-	switch t {
-	case gaia.PTypeGolang.String():
-		return gaia.PTypeGolang, nil
-	case gaia.PTypeJava.String():
-		return gaia.PTypeJava, nil
-	case gaia.PTypePython.String():
-		return gaia.PTypePython, nil
-	case gaia.PTypeCpp.String():
-		return gaia.PTypeCpp, nil
-	case gaia.PTypeRuby.String():
-		return gaia.PTypeRuby, nil
-	case gaia.PTypeNodeJS.String():
-		return gaia.PTypeNodeJS, nil
+	t, err := gaia.ParsePipelineType(n[i+len(typeDelimiter):])
+	if err != nil {
+		return gaia.PTypeUnknown, errMissingType
 	}
-
-	return gaia.PTypeUnknown, errMissingType
+	return t, nil
 }
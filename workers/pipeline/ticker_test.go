@@ -1,9 +1,11 @@
 package pipeline
 
 import (
+	"errors"
 	"github.com/gaia-pipeline/gaia/store/memdb"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -56,7 +58,7 @@ func TestCheckActivePipelines(t *testing.T) {
 	defer func() { services.MockStorageService(nil) }()
 	// Initialize global active pipelines
 	ap := NewActivePipelines()
-	GlobalActivePipelines = ap
+	SetGlobalActivePipelines(ap)
 	// Mock scheduler service
 	ms := new(mockScheduleService)
 	services.MockSchedulerService(ms)
@@ -84,6 +86,45 @@ func TestCheckActivePipelines(t *testing.T) {
 	}
 }
 
+func TestCheckActivePipelinesPluginModule(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCheckActivePipelinesPluginModule")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = &gaia.Config{
+		Logger:       hclog.NewNullLogger(),
+		DataPath:     tmp,
+		HomePath:     tmp,
+		PipelinePath: tmp,
+	}
+	gaia.Cfg.Bolt.Mode = 0600
+	if _, err := services.StorageService(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+	active := GetGlobalActivePipelines()
+	defer SetGlobalActivePipelines(active)
+	SetGlobalActivePipelines(NewActivePipelines())
+
+	// Plugin modules cannot be started to receive their jobs
+	services.MockSchedulerService(&mockScheduleService{err: errors.New("not executable")})
+	defer services.MockSchedulerService(nil)
+
+	p := gaia.Pipeline{Name: "plugin", Type: gaia.PTypeGolang, PluginModule: true}
+	execPath := GetExecPath(p)
+	if filepath.Base(execPath) != "plugin_golang.so" {
+		t.Fatalf("expected plugin suffix but got %s", execPath)
+	}
+	_ = ioutil.WriteFile(execPath, []byte("plugin"), 0600)
+	if err := writeBinaryMetadata(execPath, p); err != nil {
+		t.Fatal(err)
+	}
+
+	checkActivePipelines()
+	found := GetGlobalActivePipelines().GetByName("plugin")
+	if found == nil || !found.PluginModule || found.IsNotValid {
+		t.Fatalf("expected valid plugin module but got %+v", found)
+	}
+}
+
 func TestTurningThePollerOn(t *testing.T) {
 	tmp, _ := ioutil.TempDir("", "TestTurningThePollerOn")
 	dataDir := tmp
@@ -255,3 +296,12 @@ func TestUpdateWorker(t *testing.T) {
 		t.Fatalf("expected '%s' but got '%s'", string(gaia.WorkerActive), string(db.worker.Status))
 	}
 }
+
+func TestGetPipelineTypeRoundTrip(t *testing.T) {
+	for _, pType := range gaia.PipelineTypes() {
+		parsed, err := getPipelineType(appendTypeToNameForOS("my_pipeline", pType, "linux"))
+		if err != nil || parsed != pType {
+			t.Fatalf("expected pipeline type %s but got %s: %v", pType, parsed, err)
+		}
+	}
+}
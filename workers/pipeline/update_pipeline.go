@@ -125,6 +125,48 @@ func updatePipeline(p *gaia.Pipeline) error {
 		if out, err := cmd.CombinedOutput(); err != nil {
 			return fmt.Errorf("cannot install dependencies: %s", string(out[:]))
 		}
+	case gaia.PTypePerl:
+		// Find tar binary in path
+		path, err := exec.LookPath(tarName)
+		if err != nil {
+			return err
+		}
+
+		// Delete old folders if exist
+		tmpFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpPerlFolder, p.Name)
+		_ = os.RemoveAll(tmpFolder)
+
+		// Recreate the temp folder
+		if err := os.MkdirAll(tmpFolder, 0700); err != nil {
+			return err
+		}
+
+		// Unpack it. Dependencies are already part of the archive.
+		cmd := exec.Command(path, "-xzvf", p.ExecPath, "-C", tmpFolder)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("cannot unpack perl archive: %s", string(out[:]))
+		}
+	case gaia.PTypeR:
+		// Find tar binary in path
+		path, err := exec.LookPath(tarName)
+		if err != nil {
+			return err
+		}
+
+		// Delete old folders if exist
+		tmpFolder := filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpRFolder, p.Name)
+		_ = os.RemoveAll(tmpFolder)
+
+		// Recreate the temp folder
+		if err := os.MkdirAll(tmpFolder, 0700); err != nil {
+			return err
+		}
+
+		// Unpack it. Dependencies are already part of the archive.
+		cmd := exec.Command(path, "-xzvf", p.ExecPath, "-C", tmpFolder)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("cannot unpack R archive: %s", string(out[:]))
+		}
 	}
 
 	// Update checksum
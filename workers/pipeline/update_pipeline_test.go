@@ -127,3 +127,73 @@ func TestUpdatePipelineNodeJS(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUpdatePipelinePerl(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestUpdatePipelinePerl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+
+	p1 := gaia.Pipeline{
+		Name:    "PipelinA",
+		Type:    gaia.PTypePerl,
+		Created: time.Now(),
+	}
+
+	// Create fake test perl archive file.
+	src := filepath.Join(tmp, "PipelineA_perl")
+	p1.ExecPath = src
+	if err := ioutil.WriteFile(src, []byte("testcontent"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// fake execution commands
+	tarName = "echo"
+
+	// run
+	err = updatePipeline(&p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, gaia.TmpFolder, gaia.TmpPerlFolder, p1.Name)); err != nil {
+		t.Fatal("expected unpack folder to be created: ", err)
+	}
+}
+
+func TestUpdatePipelineR(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestUpdatePipelineR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	gaia.Cfg.HomePath = tmp
+
+	p1 := gaia.Pipeline{
+		Name:    "PipelinA",
+		Type:    gaia.PTypeR,
+		Created: time.Now(),
+	}
+
+	// Create fake test R archive file.
+	src := filepath.Join(tmp, "PipelineA_r")
+	p1.ExecPath = src
+	if err := ioutil.WriteFile(src, []byte("testcontent"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// fake execution commands
+	tarName = "echo"
+
+	// run
+	err = updatePipeline(&p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, gaia.TmpFolder, gaia.TmpRFolder, p1.Name)); err != nil {
+		t.Fatal("expected unpack folder to be created: ", err)
+	}
+}
@@ -0,0 +1,178 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	mercurialBinaryName  = "hg"
+	subversionBinaryName = "svn"
+)
+
+// VCS fetches the sources of a pipeline from a version control system.
+type VCS interface {
+	// Fetch fetches the sources of the repository of the given pipeline
+	// into its local destination. The pinned revision is fetched if set.
+	// Otherwise the selected branch is used. The fetched revision is
+	// recorded on the repository.
+	Fetch(p *gaia.CreatePipeline) error
+}
+
+// vcsImplementations are the supported version control systems.
+var vcsImplementations = map[gaia.VCSType]VCS{
+	gaia.VCSGit:        gitVCS{},
+	gaia.VCSMercurial:  mercurialVCS{},
+	gaia.VCSSubversion: subversionVCS{},
+}
+
+// newVCS returns the version control system of the given type.
+// Git is used if no type has been defined.
+func newVCS(t gaia.VCSType) (VCS, error) {
+	if t == "" {
+		t = gaia.VCSGit
+	}
+	vcs, ok := vcsImplementations[t]
+	if !ok {
+		return nil, fmt.Errorf("version control system %s is not supported", t)
+	}
+	return vcs, nil
+}
+
+// gitVCS fetches the sources from a git repository.
+type gitVCS struct{}
+
+// Fetch clones the git repository.
+func (gitVCS) Fetch(p *gaia.CreatePipeline) error {
+	return gitCloneRepo(p.Pipeline.Repo)
+}
+
+// mercurialVCS fetches the sources from a mercurial repository.
+type mercurialVCS struct{}
+
+// Fetch clones the mercurial repository and updates to the pinned revision
+// or the selected branch.
+func (mercurialVCS) Fetch(p *gaia.CreatePipeline) error {
+	repo := p.Pipeline.Repo
+	path, err := exec.LookPath(mercurialBinaryName)
+	if err != nil {
+		return fmt.Errorf("mercurial not installed on this server: cannot find %s", mercurialBinaryName)
+	}
+
+	// Credentials are passed in a temporary config file
+	env := proxyEnvironment(os.Environ())
+	if repo.Username != "" {
+		config, err := mercurialAuthConfig(repo)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(config)
+		env = append(env, "HGRCPATH="+config)
+	}
+
+	args := []string{"clone", "--noninteractive"}
+	if repo.PinnedRevision != "" {
+		args = append(args, "-u", repo.PinnedRevision)
+	} else if repo.SelectedBranch != "" {
+		args = append(args, "-b", strings.TrimPrefix(repo.SelectedBranch, refHead+"/"))
+	}
+	args = append(args, repo.URL, repo.LocalDest)
+	if output, err := executeBuildCmd(p, phasePrepare, path, args, env, ""); err != nil {
+		return fmt.Errorf("cannot clone mercurial repository: %s", vcsOutput(repo, output, err))
+	}
+
+	// Remember the cloned revision
+	args = []string{"log", "-R", repo.LocalDest, "-r", ".", "--template", "{node}"}
+	output, err := executeBuildCmd(p, phasePrepare, path, args, proxyEnvironment(os.Environ()), "")
+	if err != nil {
+		return fmt.Errorf("cannot get mercurial revision: %s", vcsOutput(repo, output, err))
+	}
+	repo.Revision = strings.TrimSpace(string(output))
+	return nil
+}
+
+// mercurialAuthConfig writes a mercurial config file with the credentials
+// of the given repository and returns its path. The caller must remove it.
+func mercurialAuthConfig(repo *gaia.GitRepo) (string, error) {
+	if strings.ContainsAny(repo.URL+repo.Username+repo.Password, "\r\n") {
+		return "", fmt.Errorf("repository url and credentials must not contain line breaks")
+	}
+	f, err := ioutil.TempFile("", "gaia-hgrc")
+	if err != nil {
+		return "", err
+	}
+	config := fmt.Sprintf("[auth]\ngaia.prefix = %s\ngaia.username = %s\ngaia.password = %s\n", repo.URL, repo.Username, repo.Password)
+	if _, err := f.WriteString(config); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// subversionVCS fetches the sources from a subversion repository.
+type subversionVCS struct{}
+
+// Fetch checks out the subversion repository at the pinned revision or
+// the latest revision. The branch is part of the url in subversion.
+func (subversionVCS) Fetch(p *gaia.CreatePipeline) error {
+	repo := p.Pipeline.Repo
+	path, err := exec.LookPath(subversionBinaryName)
+	if err != nil {
+		return fmt.Errorf("subversion not installed on this server: cannot find %s", subversionBinaryName)
+	}
+
+	// The password is read from stdin
+	var input []byte
+	args := []string{"checkout", "--non-interactive", "--no-auth-cache"}
+	if repo.Username != "" {
+		args = append(args, "--username", repo.Username, "--password-from-stdin")
+		input = []byte(repo.Password + "\n")
+	}
+	if repo.PinnedRevision != "" {
+		args = append(args, "-r", repo.PinnedRevision)
+	}
+	args = append(args, repo.URL, repo.LocalDest)
+	if output, err := executeBuildCmdWithInput(p, phasePrepare, input, path, args, proxyEnvironment(os.Environ()), ""); err != nil {
+		return fmt.Errorf("cannot check out subversion repository: %s", vcsOutput(repo, output, err))
+	}
+
+	// Remember the checked out revision
+	args = []string{"info", "--show-item", "revision", repo.LocalDest}
+	output, err := executeBuildCmd(p, phasePrepare, path, args, proxyEnvironment(os.Environ()), "")
+	if err != nil {
+		return fmt.Errorf("cannot get subversion revision: %s", vcsOutput(repo, output, err))
+	}
+	repo.Revision = strings.TrimSpace(string(output))
+	return nil
+}
+
+// commandOutput returns the output of a failed command or the error if
+// there is no output.
+func commandOutput(output []byte, err error) string {
+	if out := strings.TrimSpace(string(output)); out != "" {
+		return out
+	}
+	return err.Error()
+}
+
+// vcsOutput returns the output of a failed command of a version control
+// system like commandOutput. The password of the repository is redacted.
+func vcsOutput(repo *gaia.GitRepo, output []byte, err error) string {
+	out := commandOutput(output, err)
+	if repo.Password != "" {
+		out = strings.Replace(out, repo.Password, "***", -1)
+		out = strings.Replace(out, url.QueryEscape(repo.Password), "***", -1)
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// fakeVCSClient puts a fake client of a version control system in front
+// of the path and lets all commands print the given revision.
+func fakeVCSClient(t *testing.T, dir, name, revision string) func() {
+	bin := filepath.Join(dir, "bin")
+	_ = os.MkdirAll(bin, 0700)
+	if err := ioutil.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	currentPath := os.Getenv("PATH")
+	execCommandContext = fakeExecCommandContext
+	_ = os.Setenv("PATH", bin+string(os.PathListSeparator)+currentPath)
+	_ = os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	_ = os.Setenv("STDOUT", revision)
+	_ = os.Unsetenv("CMD_ARGS")
+	return func() {
+		execCommandContext = exec.CommandContext
+		_ = os.Setenv("PATH", currentPath)
+		_ = os.Unsetenv("GO_WANT_HELPER_PROCESS")
+		_ = os.Unsetenv("STDOUT")
+	}
+}
+
+func TestNewVCS(t *testing.T) {
+	if vcs, err := newVCS(""); err != nil || vcs != (gitVCS{}) {
+		t.Fatalf("expected git as default but got %T: %v", vcs, err)
+	}
+	if _, err := newVCS("cvs"); err == nil {
+		t.Fatal("expected error for unsupported version control system")
+	}
+}
+
+func TestMercurialFetch(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestMercurialFetch")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	defer fakeVCSClient(t, tmp, mercurialBinaryName, "f00ba4")()
+
+	p := &gaia.CreatePipeline{}
+	p.Pipeline.Repo = &gaia.GitRepo{
+		URL:            "https://hg.example.com/repo",
+		Username:       "user",
+		Password:       "secret",
+		SelectedBranch: "refs/heads/stable",
+		LocalDest:      filepath.Join(tmp, "dest"),
+	}
+	repo := p.Pipeline.Repo
+	if err := (mercurialVCS{}).Fetch(p); err != nil {
+		t.Fatal(err)
+	}
+	actualArgs := os.Getenv("CMD_ARGS")
+	expectedArgs := "clone,--noninteractive,-b,stable,https://hg.example.com/repo," + repo.LocalDest
+	if !strings.Contains(actualArgs, expectedArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedArgs, actualArgs)
+	}
+	if strings.Contains(actualArgs, "secret") {
+		t.Fatalf("expected no password in args '%s'", actualArgs)
+	}
+	if repo.Revision != "f00ba4" {
+		t.Fatalf("expected revision f00ba4 but got %s", repo.Revision)
+	}
+
+	// The pinned revision takes precedence over the branch
+	_ = os.Unsetenv("CMD_ARGS")
+	repo.PinnedRevision = "1234"
+	if err := (mercurialVCS{}).Fetch(p); err != nil {
+		t.Fatal(err)
+	}
+	if actualArgs := os.Getenv("CMD_ARGS"); !strings.Contains(actualArgs, "clone,--noninteractive,-u,1234,") {
+		t.Fatalf("expected pinned revision in args '%s'", actualArgs)
+	}
+}
+
+func TestMercurialAuthConfig(t *testing.T) {
+	repo := &gaia.GitRepo{URL: "https://hg.example.com/repo", Username: "user", Password: "secret"}
+	config, err := mercurialAuthConfig(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(config)
+	content, _ := ioutil.ReadFile(config)
+	expected := "[auth]\ngaia.prefix = https://hg.example.com/repo\ngaia.username = user\ngaia.password = secret\n"
+	if string(content) != expected {
+		t.Fatalf("expected config %q but got %q", expected, string(content))
+	}
+
+	// Line breaks would add config entries
+	repo.Password = "secret\n[hooks]"
+	if _, err := mercurialAuthConfig(repo); err == nil {
+		t.Fatal("expected error for password with line break")
+	}
+}
+
+func TestSubversionFetch(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestSubversionFetch")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	defer fakeVCSClient(t, tmp, subversionBinaryName, "42\n")()
+
+	p := &gaia.CreatePipeline{}
+	p.Pipeline.Repo = &gaia.GitRepo{
+		URL:            "https://svn.example.com/repo/trunk",
+		Username:       "user",
+		Password:       "secret",
+		PinnedRevision: "42",
+		LocalDest:      filepath.Join(tmp, "dest"),
+	}
+	repo := p.Pipeline.Repo
+	if err := (subversionVCS{}).Fetch(p); err != nil {
+		t.Fatal(err)
+	}
+	actualArgs := os.Getenv("CMD_ARGS")
+	expectedArgs := "checkout,--non-interactive,--no-auth-cache,--username,user,--password-from-stdin,-r,42,https://svn.example.com/repo/trunk," + repo.LocalDest
+	if !strings.Contains(actualArgs, expectedArgs) {
+		t.Fatalf("expected args '%s' actual args '%s'", expectedArgs, actualArgs)
+	}
+	if strings.Contains(actualArgs, "secret") {
+		t.Fatalf("expected no password in args '%s'", actualArgs)
+	}
+	if repo.Revision != "42" {
+		t.Fatalf("expected revision 42 but got %s", repo.Revision)
+	}
+}
+
+func TestVCSOutputRedactsPassword(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestVCSOutputRedactsPassword")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = new(gaia.Config)
+	defer fakeVCSClient(t, tmp, subversionBinaryName, "authorization failed for p%40ss and p@ss")()
+	_ = os.Setenv("EXIT_STATUS", "1")
+	defer os.Unsetenv("EXIT_STATUS")
+
+	p := &gaia.CreatePipeline{}
+	p.Pipeline.Repo = &gaia.GitRepo{
+		URL:       "https://svn.example.com/repo/trunk",
+		Username:  "user",
+		Password:  "p@ss",
+		LocalDest: filepath.Join(tmp, "dest"),
+	}
+	err := (subversionVCS{}).Fetch(p)
+	if err == nil || err.Error() != "cannot check out subversion repository: authorization failed for *** and ***" {
+		t.Fatalf("expected redacted error but got %v", err)
+	}
+}
+
+func TestAcquireSourceCleanTreeUnsupported(t *testing.T) {
+	p := &gaia.CreatePipeline{VCS: gaia.VCSMercurial, RequireCleanTree: true}
+	p.Pipeline.Repo = &gaia.GitRepo{}
+	if err := acquireSource(p); err == nil || !strings.Contains(err.Error(), "not supported for hg") {
+		t.Fatalf("expected unsupported clean tree check error but got: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/gaia-pipeline/gaia"
 	"gopkg.in/yaml.v2"
@@ -16,6 +17,27 @@ func createPipelineCmd(p *gaia.Pipeline) *exec.Cmd {
 	// Dependent on the pipeline type
 	switch p.Type {
 	case gaia.PTypeGolang:
+		// Plugin modules are loaded in-process and are not executable
+		if p.PluginModule {
+			gaia.Cfg.Logger.Error("go plugin modules cannot be started as plugin process", "pipeline", p.Name)
+			return nil
+		}
+
+		// WebAssembly binaries are started in the WebAssembly runtime
+		if strings.HasSuffix(p.ExecPath, wasmExtension) {
+			wasmRuntime := gaia.Cfg.WasmRuntime
+			if wasmRuntime == "" {
+				wasmRuntime = defaultWasmRuntime
+			}
+			path, err := exec.LookPath(wasmRuntime)
+			if err != nil {
+				gaia.Cfg.Logger.Error("cannot find WebAssembly runtime", "runtime", wasmRuntime, "error", err)
+				return nil
+			}
+			c.Path = path
+			c.Args = []string{path, p.ExecPath}
+			break
+		}
 		c.Path = p.ExecPath
 	case gaia.PTypeJava:
 		// Look for java executable
@@ -41,7 +63,7 @@ func createPipelineCmd(p *gaia.Pipeline) *exec.Cmd {
 			". bin/activate; exec " + pythonExecName + " -c \"import pipeline; pipeline.main()\"",
 		}
 		c.Dir = filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpPythonFolder, p.Name)
-	case gaia.PTypeCpp:
+	case gaia.PTypeCpp, gaia.PTypeHaskell:
 		c.Path = p.ExecPath
 	case gaia.PTypeRuby:
 		// Look for ruby executable
@@ -82,9 +104,42 @@ func createPipelineCmd(p *gaia.Pipeline) *exec.Cmd {
 		c.Path = path
 		c.Args = []string{
 			path,
-			"index.js",
+			entrypoint(p, nodeJSEntrypoint),
 		}
 		c.Dir = unpackedFolder
+	case gaia.PTypePerl:
+		// Look for perl executable
+		path, err := exec.LookPath(perlExecName)
+		if err != nil {
+			gaia.Cfg.Logger.Error("cannot find perl executable", "error", err)
+			return nil
+		}
+
+		// Build start command with the packaged dependencies
+		c.Path = path
+		c.Args = []string{
+			path,
+			"-Ilib",
+			"-Ilocal/lib/perl5",
+			entrypoint(p, perlEntrypoint),
+		}
+		c.Dir = filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpPerlFolder, p.Name)
+	case gaia.PTypeR:
+		// Look for Rscript executable
+		path, err := exec.LookPath(rscriptExecName)
+		if err != nil {
+			gaia.Cfg.Logger.Error("cannot find Rscript executable", "error", err)
+			return nil
+		}
+
+		// Build start command with the packaged library
+		c.Path = path
+		c.Args = []string{
+			path,
+			"-e",
+			".libPaths(c('library', .libPaths())); source('" + rStringEscaper.Replace(entrypoint(p, rEntrypoint)) + "')",
+		}
+		c.Dir = filepath.Join(gaia.Cfg.HomePath, gaia.TmpFolder, gaia.TmpRFolder, p.Name)
 	default:
 		c = nil
 	}
@@ -92,6 +147,18 @@ func createPipelineCmd(p *gaia.Pipeline) *exec.Cmd {
 	return c
 }
 
+// rStringEscaper escapes a string for an R string literal in single quotes.
+var rStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// entrypoint returns the script which starts the given pipeline.
+// The given default is used if the pipeline has no entrypoint.
+func entrypoint(p *gaia.Pipeline, defaultEntrypoint string) string {
+	if p.Entrypoint != "" {
+		return p.Entrypoint
+	}
+	return defaultEntrypoint
+}
+
 var findRubyGemCommands = []string{"specification", "--yaml"}
 
 // findRubyGemName finds the gem name of a ruby gem file.
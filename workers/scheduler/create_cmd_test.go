@@ -1,6 +1,15 @@
 package scheduler
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	hclog "github.com/hashicorp/go-hclog"
+)
 
 func TestFindRubyGemName(t *testing.T) {
 	// Adjust gubyGemName (might be not available in test container)
@@ -18,3 +27,60 @@ func TestFindRubyGemName(t *testing.T) {
 		t.Errorf("Gem name should be 'testruby' but was %s", gemName)
 	}
 }
+
+func TestCreatePipelineCmdWasm(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger(), WasmRuntime: "echo"}
+	p := &gaia.Pipeline{Name: "main", Type: gaia.PTypeGolang, ExecPath: "/plugins/main_golang.wasm"}
+	c := createPipelineCmd(p)
+	if c == nil {
+		t.Fatal("expected command for WebAssembly pipeline")
+	}
+	if len(c.Args) != 2 || filepath.Base(c.Args[0]) != "echo" || c.Args[1] != p.ExecPath {
+		t.Fatalf("expected the runtime to start the binary but got %v", c.Args)
+	}
+
+	// Other go pipelines are executed directly
+	p.ExecPath = "/plugins/main_golang"
+	if c := createPipelineCmd(p); c == nil || c.Path != p.ExecPath {
+		t.Fatalf("expected the binary to be executed but got %v", c)
+	}
+
+	gaia.Cfg.WasmRuntime = "notexistingruntime"
+	p.ExecPath = "/plugins/main_golang.wasm"
+	if c := createPipelineCmd(p); c != nil {
+		t.Fatal("expected no command without WebAssembly runtime")
+	}
+}
+
+func TestCreatePipelineCmdEntrypoint(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCreatePipelineCmdEntrypoint")
+	defer os.RemoveAll(tmp)
+	for _, name := range []string{nodeJSExecName, perlExecName, rscriptExecName} {
+		_ = ioutil.WriteFile(filepath.Join(tmp, name), nil, 0700)
+	}
+	currentPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", currentPath)
+	_ = os.Setenv("PATH", tmp)
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger(), HomePath: tmp}
+
+	// The default entrypoint is started without configured one
+	p := &gaia.Pipeline{Name: "main", Type: gaia.PTypeNodeJS}
+	if c := createPipelineCmd(p); c == nil || c.Args[len(c.Args)-1] != nodeJSEntrypoint {
+		t.Fatalf("expected default entrypoint but got %v", c)
+	}
+
+	p.Entrypoint = "src/start.js"
+	if c := createPipelineCmd(p); c == nil || c.Args[len(c.Args)-1] != "src/start.js" {
+		t.Fatalf("expected custom entrypoint but got %v", c)
+	}
+	p.Type = gaia.PTypePerl
+	p.Entrypoint = "bin/run.pl"
+	if c := createPipelineCmd(p); c == nil || c.Args[len(c.Args)-1] != "bin/run.pl" {
+		t.Fatalf("expected custom entrypoint but got %v", c)
+	}
+	p.Type = gaia.PTypeR
+	p.Entrypoint = "R/it's.R"
+	if c := createPipelineCmd(p); c == nil || !strings.HasSuffix(c.Args[len(c.Args)-1], `source('R/it\'s.R')`) {
+		t.Fatalf("expected escaped custom entrypoint but got %v", c)
+	}
+}
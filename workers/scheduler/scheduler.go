@@ -55,6 +55,27 @@ var (
 
 	// NodeJS binary name
 	nodeJSExecName = "node"
+
+	// NodeJS script which is executed to start a NodeJS pipeline
+	nodeJSEntrypoint = "index.js"
+
+	// Perl executable name
+	perlExecName = "perl"
+
+	// Perl script which is executed to start a perl pipeline
+	perlEntrypoint = "main.pl"
+
+	// Rscript executable name
+	rscriptExecName = "Rscript"
+
+	// R script which is executed to start a R pipeline
+	rEntrypoint = "main.R"
+
+	// Runtime which starts WebAssembly pipelines if none has been configured
+	defaultWasmRuntime = "wasmtime"
+
+	// Extension of pipelines built as WebAssembly binary
+	wasmExtension = ".wasm"
 )
 
 // GaiaScheduler is a job scheduler for gaia pipeline runs.
@@ -340,6 +361,9 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, args []*gaia.Argument) (*
 	// increment by one
 	highestID++
 
+	// Default arguments of the pipeline are used if not given
+	args = mergeArgs(p.DefaultArgs, args)
+
 	// Get jobs
 	jobs, err := s.getPipelineJobs(p)
 	if err != nil {
@@ -395,6 +419,29 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, args []*gaia.Argument) (*
 	return &run, s.storeService.PipelinePutRun(&run)
 }
 
+// mergeArgs merges the given arguments into the default arguments.
+// Given arguments override default arguments with the same key.
+func mergeArgs(defaults, args []*gaia.Argument) []*gaia.Argument {
+	if len(defaults) == 0 {
+		return args
+	}
+
+	merged := make([]*gaia.Argument, 0, len(defaults)+len(args))
+	for _, defaultArg := range defaults {
+		overridden := false
+		for _, arg := range args {
+			if arg.Key == defaultArg.Key {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, defaultArg)
+		}
+	}
+	return append(merged, args...)
+}
+
 // executeJob executes a job and informs via triggerSave that the job can be saved to the store.
 // This method is blocking.
 func executeJob(j gaia.Job, pS plugin.Plugin, triggerSave chan gaia.Job) {
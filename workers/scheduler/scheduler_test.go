@@ -402,6 +402,46 @@ func TestSchedulePipeline(t *testing.T) {
 	}
 }
 
+func TestSchedulePipelineDefaultArgs(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestSchedulePipelineDefaultArgs")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	gaia.Cfg.Worker = 2
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	p.DefaultArgs = []*gaia.Argument{
+		{Key: "firstarg", Value: "default"},
+		{Key: "secondarg", Value: "default"},
+	}
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Init()
+	run, err := s.SchedulePipeline(&p, []*gaia.Argument{{Key: "secondarg", Value: "given"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{}
+	for _, arg := range run.Jobs[0].Args {
+		values[arg.Key] = arg.Value
+	}
+	if values["firstarg"] != "default" {
+		t.Fatalf("expected default value for firstarg but got '%s'", values["firstarg"])
+	}
+	if values["secondarg"] != "given" {
+		t.Fatalf("expected given value for secondarg but got '%s'", values["secondarg"])
+	}
+}
+
 func TestSchedulePipelineParallel(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	storeInstance := store.NewBoltStore()
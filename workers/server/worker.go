@@ -79,7 +79,7 @@ func (w *WorkServer) GetWork(workInst *pb.WorkerInstance, serv pb.Worker_GetWork
 		}
 
 		// Lookup pipeline from run
-		for _, p := range pipeline.GlobalActivePipelines.GetAll() {
+		for _, p := range pipeline.GetGlobalActivePipelines().GetAll() {
 			if p.ID == scheduled.PipelineID {
 				gRPCPipelineRun.ShaSum = p.SHA256Sum
 				gRPCPipelineRun.PipelineName = filepath.Base(p.ExecPath)
@@ -298,7 +298,7 @@ func (w *WorkServer) StreamBinary(pipelineRun *pb.PipelineRun, serv pb.Worker_St
 
 	// Lookup related pipeline
 	var foundPipeline *gaia.Pipeline
-	pipelines := pipeline.GlobalActivePipelines.GetAll()
+	pipelines := pipeline.GetGlobalActivePipelines().GetAll()
 	for id := range pipelines {
 		if pipelines[id].ID == int(pipelineRun.PipelineId) {
 			foundPipeline = &pipelines[id]
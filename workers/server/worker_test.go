@@ -142,8 +142,8 @@ func TestGetWork(t *testing.T) {
 	services.MockMemDBService(&mockMemDBService{})
 
 	// Init global active pipelines slice
-	pipeline.GlobalActivePipelines = pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines.Append(gaia.Pipeline{ID: 1, SHA256Sum: []byte("testbytes"), Type: gaia.PTypeGolang, ExecPath: "execpath"})
+	pipeline.SetGlobalActivePipelines(pipeline.NewActivePipelines())
+	pipeline.GetGlobalActivePipelines().Append(gaia.Pipeline{ID: 1, SHA256Sum: []byte("testbytes"), Type: gaia.PTypeGolang, ExecPath: "execpath"})
 
 	// Mock gRPC server
 	mw := mockGetWorkServ{}
@@ -256,8 +256,8 @@ func TestStreamBinary(t *testing.T) {
 	}
 
 	// Init global active pipelines slice
-	pipeline.GlobalActivePipelines = pipeline.NewActivePipelines()
-	pipeline.GlobalActivePipelines.Append(gaia.Pipeline{ID: 1, SHA256Sum: []byte("testbytes"), Type: gaia.PTypeGolang, ExecPath: testPipeline})
+	pipeline.SetGlobalActivePipelines(pipeline.NewActivePipelines())
+	pipeline.GetGlobalActivePipelines().Append(gaia.Pipeline{ID: 1, SHA256Sum: []byte("testbytes"), Type: gaia.PTypeGolang, ExecPath: testPipeline})
 
 	// Mock gRPC server
 	mw := mockStreamBinaryServ{}